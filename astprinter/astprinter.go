@@ -9,9 +9,9 @@ import (
 
 type Printer struct{}
 
-// VisitVariableExpr implements expr.ExprVisitor.
-func (*Printer) VisitVariableExpr(expr *expr.Variable) (interface{}, error) {
-	panic("unimplemented")
+func (p *Printer) VisitAssignExpr(expr *expr.Assign) (interface{}, error) {
+	v, _ := p.parenthesize("= "+expr.Name.Lexeme, expr.Value)
+	return v, nil
 }
 
 func (p *Printer) VisitBinaryExpr(expr *expr.Binary) (interface{}, error) {
@@ -19,11 +19,31 @@ func (p *Printer) VisitBinaryExpr(expr *expr.Binary) (interface{}, error) {
 	return v, nil
 }
 
+func (p *Printer) VisitCallExpr(call *expr.Call) (interface{}, error) {
+	v, _ := p.parenthesize("call", append([]expr.Expr{call.Callee}, call.Arguments...)...)
+	return v, nil
+}
+
+func (p *Printer) VisitGetExpr(expr *expr.Get) (interface{}, error) {
+	v, _ := p.parenthesize(". "+expr.Name.Lexeme, expr.Object)
+	return v, nil
+}
+
 func (p *Printer) VisitGroupingExpr(expr *expr.Grouping) (interface{}, error) {
 	v, _ := p.parenthesize("grouping", expr.Expression)
 	return v, nil
 }
 
+func (p *Printer) VisitIndexExpr(expr *expr.Index) (interface{}, error) {
+	v, _ := p.parenthesize("[]", expr.Object, expr.Index)
+	return v, nil
+}
+
+func (p *Printer) VisitListExpr(expr *expr.List) (interface{}, error) {
+	v, _ := p.parenthesize("list", expr.Elements...)
+	return v, nil
+}
+
 func (p *Printer) VisitLiteralExpr(expr *expr.Literal) (interface{}, error) {
 	if expr.Value == nil {
 		return "nil", nil
@@ -31,11 +51,52 @@ func (p *Printer) VisitLiteralExpr(expr *expr.Literal) (interface{}, error) {
 	return fmt.Sprintf("%v", expr.Value), nil
 }
 
+func (p *Printer) VisitLogicalExpr(expr *expr.Logical) (interface{}, error) {
+	v, _ := p.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right)
+	return v, nil
+}
+
+func (p *Printer) VisitMapExpr(m *expr.Map) (interface{}, error) {
+	entries := []expr.Expr{}
+	for n, key := range m.Keys {
+		entries = append(entries, key, m.Values[n])
+	}
+	v, _ := p.parenthesize("map", entries...)
+	return v, nil
+}
+
+func (p *Printer) VisitSetExpr(expr *expr.Set) (interface{}, error) {
+	v, _ := p.parenthesize("= . "+expr.Name.Lexeme, expr.Object, expr.Value)
+	return v, nil
+}
+
+func (p *Printer) VisitSetIndexExpr(expr *expr.SetIndex) (interface{}, error) {
+	v, _ := p.parenthesize("= []", expr.Object, expr.Index, expr.Value)
+	return v, nil
+}
+
+func (p *Printer) VisitStringifyExpr(expr *expr.Stringify) (interface{}, error) {
+	v, _ := p.parenthesize("str", expr.Expression)
+	return v, nil
+}
+
+func (p *Printer) VisitSuperExpr(expr *expr.Super) (interface{}, error) {
+	return "super." + expr.Method.Lexeme, nil
+}
+
+func (p *Printer) VisitThisExpr(expr *expr.This) (interface{}, error) {
+	return "this", nil
+}
+
 func (p *Printer) VisitUnaryExpr(expr *expr.Unary) (interface{}, error) {
 	v, _ := p.parenthesize(expr.Operator.Lexeme, expr.Right)
 	return v, nil
 }
 
+func (p *Printer) VisitVariableExpr(expr *expr.Variable) (interface{}, error) {
+	return expr.Name.Lexeme, nil
+}
+
 func (p *Printer) parenthesize(name string, exprs ...expr.Expr) (string, error) {
 	var builder strings.Builder
 
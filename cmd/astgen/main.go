@@ -16,16 +16,35 @@ func main() {
 	}
 	outputDir := os.Args[1]
 	err := defineAst(outputDir, "Expr", []string{
+		"Assign   : token.Token Name, Expr Value, Binding Binding",
 		"Binary   : Expr Left, token.Token Operator, Expr Right",
+		"Call     : Expr Callee, token.Token Paren, []Expr Arguments",
+		"Get      : Expr Object, token.Token Name",
 		"Grouping : Expr Expression",
+		"Index    : Expr Object, token.Token Bracket, Expr Index",
+		"List     : token.Token Bracket, []Expr Elements",
 		"Literal  : Object Value",
+		"Logical  : Expr Left, token.Token Operator, Expr Right",
+		"Map      : token.Token Brace, []Expr Keys, []Expr Values",
+		"Set      : Expr Object, token.Token Name, Expr Value",
+		"SetIndex : Expr Object, token.Token Bracket, Expr Index, Expr Value",
+		"Stringify : Expr Expression",
+		"Super    : token.Token Keyword, token.Token Method, Binding Binding",
+		"This     : token.Token Keyword, Binding Binding",
 		"Unary    : token.Token Operator, Expr Right",
-		"Variable : token.Token Name",
+		"Variable : token.Token Name, Binding Binding",
 	})
 	defineAst(outputDir, "Stmt", []string{
+		"Block : []Stmt Statements",
+		"Class : token.Token Name, *expr.Variable Superclass, []*Function Methods",
 		"Expression : expr.Expr Expression",
+		"ForIn : token.Token Name, token.Token In, expr.Expr Iterable, Stmt Body",
+		"Function : token.Token Name, []token.Token Params, []Stmt Body",
+		"If : expr.Expr Condition, Stmt ThenBranch, Stmt ElseBranch",
 		"Print : expr.Expr Expression",
+		"Return : token.Token Keyword, expr.Expr Value",
 		"Var : token.Token Name, expr.Expr Initializer",
+		"While : expr.Expr Condition, Stmt Body",
 	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error generating AST definition: %s\n", err)
@@ -59,6 +78,10 @@ func defineAst(baseOutputDir string, baseName string, types []string) error {
 
 	builder.WriteString("import (\n")
 	for _, pkg := range imports {
+		// a package can't import itself
+		if filepath.Base(pkg) == packageName {
+			continue
+		}
 		builder.WriteString(fmt.Sprintf("\t\"%s\"\n", pkg))
 	}
 	builder.WriteString(")\n\n")
@@ -2,52 +2,141 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/joshbochu/golox/compiler"
+	"github.com/joshbochu/golox/expr"
 	"github.com/joshbochu/golox/interpreter"
 	"github.com/joshbochu/golox/loxerror"
 	"github.com/joshbochu/golox/parser"
+	"github.com/joshbochu/golox/resolver"
 	"github.com/joshbochu/golox/scanner"
+	"github.com/joshbochu/golox/stmt"
+	"github.com/joshbochu/golox/vm"
 )
 
+var useVM = flag.Bool("vm", false, "run on the bytecode virtual machine instead of the tree-walking interpreter")
+
 func main() {
-	switch len(os.Args) {
-	case 1: // "./main"
+	flag.Usage = func() {
+		fmt.Println("Usage: lox [-vm] [script]")
+		fmt.Println("       lox disasm script")
+		fmt.Println("       lox compile script")
+	}
+	flag.Parse()
+
+	switch flag.Arg(0) {
+	case "disasm", "compile":
+		// a subcommand, never a script named after one
+		if flag.NArg() != 2 {
+			flag.Usage()
+			os.Exit(64)
+		}
+		if flag.Arg(0) == "disasm" {
+			disassembleFile(flag.Arg(1))
+		} else {
+			compileFile(flag.Arg(1))
+		}
+		return
+	}
+
+	switch flag.NArg() {
+	case 0: // "./main"
 		runPrompt()
-	case 2: // "./main fileName"
-		runFile(os.Args[1])
+	case 1: // "./main fileName"
+		runFile(flag.Arg(0))
 	default: // "./main fileName ..."
-		fmt.Println("Usage: lox [script]")
+		flag.Usage()
 		os.Exit(64)
 	}
 }
 
-func runFile(path string) {
+// backend runs programs that scanned, parsed, and resolved without errors.
+type backend interface {
+	resolver.Locals
+	execute(statements []stmt.Stmt)
+}
+
+// treeWalker runs programs directly on the AST.
+type treeWalker struct {
+	*interpreter.Interpreter
+}
+
+func (t treeWalker) execute(statements []stmt.Stmt) {
+	t.Interpret(statements)
+}
+
+// discardLocals ignores the resolver's results for the bytecode compiler,
+// which resolves variables itself.
+type discardLocals struct{}
+
+func (discardLocals) Resolve(expr expr.Expr, depth int) {}
+
+// bytecode compiles programs and runs them on the VM.
+type bytecode struct {
+	discardLocals
+	vm       *vm.VM
+	reporter *loxerror.PrintReporter
+}
+
+func (b bytecode) execute(statements []stmt.Stmt) {
+	function, err := compiler.NewCompiler(b.reporter).Compile(statements)
+	if err != nil {
+		return
+	}
+	b.vm.Interpret(function)
+}
+
+func newBackend(reporter *loxerror.PrintReporter) backend {
+	if *useVM {
+		return bytecode{vm: vm.NewVM(reporter), reporter: reporter}
+	}
+	return treeWalker{interpreter.NewInterpreter(reporter)}
+}
+
+func readFile(path string) string {
 	bytes, err := os.ReadFile(path)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error %v\n", err)
 		os.Exit(65)
 	}
-	source := string(bytes)
-	run(source)
-	if loxerror.LoxError.HadError {
+	return string(bytes)
+}
+
+func runFile(path string) {
+	if filepath.Ext(path) == compiledExt {
+		runCompiled(path)
+		return
+	}
+	source := readFile(path)
+	reporter := loxerror.NewPrintReporter(os.Stderr)
+	reporter.SetSource(path, source)
+	run(source, reporter, newBackend(reporter))
+	if reporter.HadError {
 		os.Exit(65)
 	}
+	if reporter.HadRuntimeError {
+		os.Exit(70)
+	}
 }
 
 func runPrompt() {
 	scanner := bufio.NewScanner(os.Stdin)
+	reporter := loxerror.NewPrintReporter(os.Stderr)
+	backend := newBackend(reporter)
 	fmt.Print("> ")
 	for {
 		if !scanner.Scan() {
 			break
 		}
 		line := scanner.Text()
-		run(line)
-		if loxerror.LoxError.HadError {
-			loxerror.LoxError.HadError = false
-		}
+		reporter.SetSource("", line)
+		run(line, reporter, backend)
+		reporter.Reset()
 		fmt.Print("> ")
 	}
 	if err := scanner.Err(); err != nil {
@@ -55,15 +144,94 @@ func runPrompt() {
 	}
 }
 
-// temp
-func run(source string) {
-	scanner := scanner.NewScanner(source)
+// compiledExt marks files written by "lox compile", which always run on the VM.
+const compiledExt = ".loxc"
+
+// compileFile compiles the script at path and writes the bytecode next to it
+// with the extension replaced by .loxc.
+func compileFile(path string) {
+	function := compileSource(path)
+	out := strings.TrimSuffix(path, filepath.Ext(path)) + compiledExt
+	file, err := os.Create(out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		os.Exit(74)
+	}
+	err = compiler.Encode(file, function)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		os.Exit(74)
+	}
+}
+
+// runCompiled loads a .loxc file and runs it on the VM.
+func runCompiled(path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		os.Exit(65)
+	}
+	defer file.Close()
+	function, err := compiler.Decode(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error %s: %v\n", path, err)
+		os.Exit(65)
+	}
+
+	reporter := loxerror.NewPrintReporter(os.Stderr)
+	reporter.SetSource(path, "")
+	vm.NewVM(reporter).Interpret(function)
+	if reporter.HadRuntimeError {
+		os.Exit(70)
+	}
+}
+
+// compileSource compiles the script at path, exiting if it has errors.
+func compileSource(path string) *compiler.Function {
+	source := readFile(path)
+	reporter := loxerror.NewPrintReporter(os.Stderr)
+	reporter.SetSource(path, source)
+	statements, ok := analyze(source, reporter, discardLocals{})
+	if !ok {
+		os.Exit(65)
+	}
+	function, err := compiler.NewCompiler(reporter).Compile(statements)
+	if err != nil {
+		os.Exit(65)
+	}
+	return function
+}
+
+// disassembleFile compiles the script at path and prints its bytecode
+// instead of running it.
+func disassembleFile(path string) {
+	compiler.Disassemble(os.Stdout, compileSource(path))
+}
+
+func run(source string, reporter *loxerror.PrintReporter, backend backend) {
+	if statements, ok := analyze(source, reporter, backend); ok {
+		backend.execute(statements)
+	}
+}
+
+// analyze scans, parses, and resolves source, reporting whether it is free
+// of static errors.
+func analyze(source string, reporter *loxerror.PrintReporter, locals resolver.Locals) ([]stmt.Stmt, bool) {
+	scanner := scanner.NewScanner(source, reporter)
 	tokens := scanner.ScanTokens()
-	parser := parser.NewParser(tokens)
-	statements, err := parser.Parse()
-	if err != nil && loxerror.LoxError.HadError {
-		return
+	parser := parser.NewParser(tokens, reporter)
+	statements, _ := parser.Parse()
+	// stop if there was a syntax error, including ones from the scanner
+	if reporter.HadError {
+		return nil, false
+	}
+	resolver := resolver.NewResolver(locals, reporter)
+	resolver.Resolve(statements)
+	if reporter.HadError {
+		return nil, false
 	}
-	interpreter := interpreter.NewInterpreter()
-	interpreter.Interpret(statements)
+	return statements, true
 }
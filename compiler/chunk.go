@@ -0,0 +1,50 @@
+package compiler
+
+import "sort"
+
+// LineStart marks the offset of the first instruction compiled from Line.
+// Consecutive instructions usually share a line, so the line table only
+// records where the line changes.
+type LineStart struct {
+	Offset int
+	Line   int
+}
+
+// Chunk is the bytecode for a single function along with the constants it
+// refers to.
+type Chunk struct {
+	Code      []byte
+	Constants []Value
+	Lines     []LineStart
+}
+
+// Write appends a byte of code compiled from line.
+func (c *Chunk) Write(b byte, line int) {
+	if n := len(c.Lines); n == 0 || c.Lines[n-1].Line != line {
+		c.Lines = append(c.Lines, LineStart{Offset: len(c.Code), Line: line})
+	}
+	c.Code = append(c.Code, b)
+}
+
+// AddConstant appends value to the constant pool and returns its index.
+func (c *Chunk) AddConstant(value Value) int {
+	c.Constants = append(c.Constants, value)
+	return len(c.Constants) - 1
+}
+
+// Line returns the source line the byte at offset was compiled from.
+func (c *Chunk) Line(offset int) int {
+	// the last run starting at or before offset
+	i := sort.Search(len(c.Lines), func(i int) bool {
+		return c.Lines[i].Offset > offset
+	})
+	if i == 0 {
+		return 0
+	}
+	return c.Lines[i-1].Line
+}
+
+// ReadShort decodes the two-byte operand at offset.
+func (c *Chunk) ReadShort(offset int) int {
+	return int(c.Code[offset])<<8 | int(c.Code[offset+1])
+}
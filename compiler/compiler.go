@@ -0,0 +1,693 @@
+package compiler
+
+import (
+	"errors"
+
+	"github.com/joshbochu/golox/expr"
+	"github.com/joshbochu/golox/loxerror"
+	"github.com/joshbochu/golox/stmt"
+	"github.com/joshbochu/golox/token"
+)
+
+// Operand limits of the instruction encoding.
+const (
+	maxLocals    = 256
+	maxUpvalues  = 256
+	maxConstants = 1 << 16
+	maxJump      = 1<<16 - 1
+	maxElements  = 1<<16 - 1
+)
+
+type functionType int
+
+const (
+	functionTypeScript functionType = iota
+	functionTypeFunction
+	functionTypeInitializer
+	functionTypeMethod
+)
+
+type local struct {
+	name string
+	// depth is the scope depth the local was declared at, or -1 while its
+	// initializer is being compiled.
+	depth      int
+	isCaptured bool
+}
+
+type upvalue struct {
+	index   byte
+	isLocal bool
+}
+
+// functionCompiler holds the state of one function being compiled. They
+// form a stack through enclosing as function declarations nest.
+type functionCompiler struct {
+	enclosing  *functionCompiler
+	function   *Function
+	kind       functionType
+	locals     []local
+	upvalues   []upvalue
+	scopeDepth int
+	// strings maps each string constant to its index so names used many
+	// times share one slot in the constant pool.
+	strings map[string]int
+}
+
+// Compiler lowers resolved statements to bytecode. Static errors are assumed
+// to have been caught by the resolver already; the compiler only reports
+// programs that exceed the limits of the instruction encoding.
+type Compiler struct {
+	current *functionCompiler
+	// line is the source line attached to the code being emitted.
+	line     int
+	errs     []error
+	reporter loxerror.Reporter
+}
+
+func NewCompiler(reporter loxerror.Reporter) *Compiler {
+	return &Compiler{
+		line:     1,
+		reporter: reporter,
+	}
+}
+
+// Compile compiles a whole program into the function for its top level and
+// returns every error found, joined.
+func (c *Compiler) Compile(statements []stmt.Stmt) (*Function, error) {
+	c.beginFunction("", functionTypeScript)
+	for _, statement := range statements {
+		c.compileStmt(statement)
+	}
+	function, _ := c.endFunction()
+	return function, errors.Join(c.errs...)
+}
+
+func (c *Compiler) compileStmt(statement stmt.Stmt) {
+	statement.Accept(c)
+}
+
+func (c *Compiler) compileExpr(expression expr.Expr) {
+	expression.Accept(c)
+}
+
+func (c *Compiler) beginFunction(name string, kind functionType) {
+	c.current = &functionCompiler{
+		enclosing: c.current,
+		function:  NewFunction(name),
+		kind:      kind,
+		strings:   make(map[string]int),
+	}
+	// slot zero holds the function being called, or the receiver of a method
+	slot := ""
+	if kind == functionTypeMethod || kind == functionTypeInitializer {
+		slot = "this"
+	}
+	c.current.locals = append(c.current.locals, local{name: slot, depth: 0})
+}
+
+// endFunction finishes the current function and returns it along with the
+// upvalues its closure captures.
+func (c *Compiler) endFunction() (*Function, []upvalue) {
+	c.emitReturn()
+	finished := c.current
+	c.current = finished.enclosing
+	return finished.function, finished.upvalues
+}
+
+func (c *Compiler) function(declaration *stmt.Function, kind functionType) {
+	c.beginFunction(declaration.Name.Lexeme, kind)
+	c.beginScope()
+	for _, param := range declaration.Params {
+		c.current.function.Arity++
+		c.declareVariable(param)
+		c.markInitialized()
+	}
+	for _, statement := range declaration.Body {
+		c.compileStmt(statement)
+	}
+	// no endScope, the frame's slots are discarded when it returns
+	function, upvalues := c.endFunction()
+
+	c.line = declaration.Name.Line
+	c.emitOp(OpClosure)
+	c.emitShort(c.makeConstant(ObjectValue(function)))
+	for _, upvalue := range upvalues {
+		if upvalue.isLocal {
+			c.emitByte(1)
+		} else {
+			c.emitByte(0)
+		}
+		c.emitByte(upvalue.index)
+	}
+}
+
+func (c *Compiler) beginScope() {
+	c.current.scopeDepth++
+}
+
+// endScope discards the locals of the innermost scope, moving those that
+// closures captured off the stack.
+func (c *Compiler) endScope() {
+	fc := c.current
+	fc.scopeDepth--
+	for len(fc.locals) > 0 && fc.locals[len(fc.locals)-1].depth > fc.scopeDepth {
+		if fc.locals[len(fc.locals)-1].isCaptured {
+			c.emitOp(OpCloseUpvalue)
+		} else {
+			c.emitOp(OpPop)
+		}
+		fc.locals = fc.locals[:len(fc.locals)-1]
+	}
+}
+
+// declareVariable adds a local for name, unless it is being declared at the
+// top level where it is a global instead.
+func (c *Compiler) declareVariable(name token.Token) {
+	if c.current.scopeDepth == 0 {
+		return
+	}
+	c.addLocal(name)
+}
+
+func (c *Compiler) addLocal(name token.Token) {
+	if len(c.current.locals) == maxLocals {
+		c.errorAt(name, "Too many local variables in function.")
+		return
+	}
+	c.current.locals = append(c.current.locals, local{name: name.Lexeme, depth: -1})
+}
+
+func (c *Compiler) markInitialized() {
+	if c.current.scopeDepth == 0 {
+		return
+	}
+	c.current.locals[len(c.current.locals)-1].depth = c.current.scopeDepth
+}
+
+// defineVariable makes the value on top of the stack the variable's value.
+// Locals already live in that slot, globals are stored under their name.
+func (c *Compiler) defineVariable(name token.Token) {
+	if c.current.scopeDepth > 0 {
+		c.markInitialized()
+		return
+	}
+	c.line = name.Line
+	c.emitOp(OpDefineGlobal)
+	c.emitShort(c.identifierConstant(name.Lexeme))
+}
+
+func resolveLocal(fc *functionCompiler, name string) int {
+	for i := len(fc.locals) - 1; i >= 0; i-- {
+		if fc.locals[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// resolveUpvalue finds name in an enclosing function, threading an upvalue
+// through every function in between. It returns -1 for globals.
+func (c *Compiler) resolveUpvalue(fc *functionCompiler, name token.Token) int {
+	if fc.enclosing == nil {
+		return -1
+	}
+	if local := resolveLocal(fc.enclosing, name.Lexeme); local != -1 {
+		fc.enclosing.locals[local].isCaptured = true
+		return c.addUpvalue(fc, name, byte(local), true)
+	}
+	if upvalue := c.resolveUpvalue(fc.enclosing, name); upvalue != -1 {
+		return c.addUpvalue(fc, name, byte(upvalue), false)
+	}
+	return -1
+}
+
+func (c *Compiler) addUpvalue(fc *functionCompiler, name token.Token, index byte, isLocal bool) int {
+	for i, upvalue := range fc.upvalues {
+		if upvalue.index == index && upvalue.isLocal == isLocal {
+			return i
+		}
+	}
+	if len(fc.upvalues) == maxUpvalues {
+		c.errorAt(name, "Too many closure variables in function.")
+		return 0
+	}
+	fc.upvalues = append(fc.upvalues, upvalue{index: index, isLocal: isLocal})
+	fc.function.UpvalueCount = len(fc.upvalues)
+	return len(fc.upvalues) - 1
+}
+
+// namedVariable loads the variable called name, or stores value in it when
+// value isn't nil.
+func (c *Compiler) namedVariable(name token.Token, value expr.Expr) {
+	var getOp, setOp OpCode
+	wide := false
+	arg := resolveLocal(c.current, name.Lexeme)
+	if arg != -1 {
+		getOp, setOp = OpGetLocal, OpSetLocal
+	} else if arg = c.resolveUpvalue(c.current, name); arg != -1 {
+		getOp, setOp = OpGetUpvalue, OpSetUpvalue
+	} else {
+		arg = c.identifierConstant(name.Lexeme)
+		getOp, setOp = OpGetGlobal, OpSetGlobal
+		wide = true
+	}
+
+	op := getOp
+	if value != nil {
+		c.compileExpr(value)
+		op = setOp
+	}
+	c.line = name.Line
+	c.emitOp(op)
+	if wide {
+		c.emitShort(arg)
+	} else {
+		c.emitByte(byte(arg))
+	}
+}
+
+func (c *Compiler) identifierConstant(name string) int {
+	if index, ok := c.current.strings[name]; ok {
+		return index
+	}
+	index := c.makeConstant(ObjectValue(name))
+	c.current.strings[name] = index
+	return index
+}
+
+func (c *Compiler) makeConstant(value Value) int {
+	chunk := c.current.function.Chunk
+	if len(chunk.Constants) == maxConstants {
+		c.error("Too many constants in one chunk.")
+		return 0
+	}
+	return chunk.AddConstant(value)
+}
+
+func (c *Compiler) emitByte(b byte) {
+	c.current.function.Chunk.Write(b, c.line)
+}
+
+func (c *Compiler) emitOp(op OpCode) {
+	c.emitByte(byte(op))
+}
+
+func (c *Compiler) emitShort(n int) {
+	c.emitByte(byte(n >> 8))
+	c.emitByte(byte(n))
+}
+
+func (c *Compiler) emitConstant(value Value) {
+	c.emitOp(OpConstant)
+	c.emitShort(c.makeConstant(value))
+}
+
+// emitReturn returns nil, or the receiver from an initializer.
+func (c *Compiler) emitReturn() {
+	if c.current.kind == functionTypeInitializer {
+		c.emitOp(OpGetLocal)
+		c.emitByte(0)
+	} else {
+		c.emitOp(OpNil)
+	}
+	c.emitOp(OpReturn)
+}
+
+// emitJump emits a jump with a placeholder offset and returns the offset's
+// position for patchJump.
+func (c *Compiler) emitJump(op OpCode) int {
+	c.emitOp(op)
+	c.emitShort(0xffff)
+	return len(c.current.function.Chunk.Code) - 2
+}
+
+// patchJump points the jump at offset to the next instruction emitted.
+func (c *Compiler) patchJump(offset int) {
+	code := c.current.function.Chunk.Code
+	jump := len(code) - offset - 2
+	if jump > maxJump {
+		c.error("Too much code to jump over.")
+	}
+	code[offset] = byte(jump >> 8)
+	code[offset+1] = byte(jump)
+}
+
+func (c *Compiler) emitLoop(loopStart int) {
+	c.emitOp(OpLoop)
+	offset := len(c.current.function.Chunk.Code) - loopStart + 2
+	if offset > maxJump {
+		c.error("Loop body too large.")
+	}
+	c.emitShort(offset)
+}
+
+// error reports a problem at the line being compiled.
+func (c *Compiler) error(message string) {
+	c.reporter.Report(loxerror.AtLine(c.line, loxerror.CodeCompile, message))
+	c.errs = append(c.errs, loxerror.NewCompileError(c.line, message))
+}
+
+func (c *Compiler) errorAt(name token.Token, message string) {
+	c.reporter.Report(loxerror.AtToken(name, loxerror.CodeCompile, message))
+	c.errs = append(c.errs, loxerror.NewCompileError(name.Line, message))
+}
+
+func (c *Compiler) VisitBlockStmt(stmt *stmt.Block) (interface{}, error) {
+	c.beginScope()
+	for _, statement := range stmt.Statements {
+		c.compileStmt(statement)
+	}
+	c.endScope()
+	return nil, nil
+}
+
+func (c *Compiler) VisitClassStmt(stmt *stmt.Class) (interface{}, error) {
+	c.line = stmt.Name.Line
+	nameConstant := c.identifierConstant(stmt.Name.Lexeme)
+	c.declareVariable(stmt.Name)
+	c.emitOp(OpClass)
+	c.emitShort(nameConstant)
+	c.defineVariable(stmt.Name)
+
+	if stmt.Superclass != nil {
+		// the superclass stays on the stack as a local named "super" that
+		// the methods close over
+		c.namedVariable(stmt.Superclass.Name, nil)
+		c.beginScope()
+		c.addLocal(token.Token{Type: token.SUPER, Lexeme: "super", Line: stmt.Superclass.Name.Line})
+		c.markInitialized()
+
+		c.namedVariable(stmt.Name, nil)
+		c.line = stmt.Superclass.Name.Line
+		c.emitOp(OpInherit)
+	}
+
+	// the class stays on the stack while its methods are attached
+	c.namedVariable(stmt.Name, nil)
+	for _, method := range stmt.Methods {
+		kind := functionTypeMethod
+		if method.Name.Lexeme == "init" {
+			kind = functionTypeInitializer
+		}
+		c.function(method, kind)
+		c.emitOp(OpMethod)
+		c.emitShort(c.identifierConstant(method.Name.Lexeme))
+	}
+	c.emitOp(OpPop)
+
+	if stmt.Superclass != nil {
+		c.endScope()
+	}
+	return nil, nil
+}
+
+func (c *Compiler) VisitExpressionStmt(stmt *stmt.Expression) (interface{}, error) {
+	c.compileExpr(stmt.Expression)
+	c.emitOp(OpPop)
+	return nil, nil
+}
+
+func (c *Compiler) VisitForInStmt(stmt *stmt.ForIn) (interface{}, error) {
+	c.beginScope()
+	c.compileExpr(stmt.Iterable)
+	c.line = stmt.In.Line
+	c.emitOp(OpIterate)
+	// the iterator lives in a local Lox code can't name
+	c.addLocal(token.Token{Line: stmt.In.Line})
+	c.markInitialized()
+
+	loopStart := len(c.current.function.Chunk.Code)
+	c.line = stmt.In.Line
+	exitJump := c.emitJump(OpForIter)
+	// a fresh variable each time round, so closures capture one value each
+	c.beginScope()
+	c.addLocal(stmt.Name)
+	c.markInitialized()
+	c.compileStmt(stmt.Body)
+	c.endScope()
+	c.emitLoop(loopStart)
+
+	c.patchJump(exitJump)
+	c.endScope()
+	return nil, nil
+}
+
+func (c *Compiler) VisitFunctionStmt(stmt *stmt.Function) (interface{}, error) {
+	c.declareVariable(stmt.Name)
+	// initialized straight away so the function can refer to itself recursively
+	c.markInitialized()
+	c.function(stmt, functionTypeFunction)
+	c.defineVariable(stmt.Name)
+	return nil, nil
+}
+
+func (c *Compiler) VisitIfStmt(stmt *stmt.If) (interface{}, error) {
+	c.compileExpr(stmt.Condition)
+	thenJump := c.emitJump(OpJumpIfFalse)
+	c.emitOp(OpPop)
+	c.compileStmt(stmt.ThenBranch)
+
+	elseJump := c.emitJump(OpJump)
+	c.patchJump(thenJump)
+	c.emitOp(OpPop)
+	if stmt.ElseBranch != nil {
+		c.compileStmt(stmt.ElseBranch)
+	}
+	c.patchJump(elseJump)
+	return nil, nil
+}
+
+func (c *Compiler) VisitPrintStmt(stmt *stmt.Print) (interface{}, error) {
+	c.compileExpr(stmt.Expression)
+	c.emitOp(OpPrint)
+	return nil, nil
+}
+
+func (c *Compiler) VisitReturnStmt(stmt *stmt.Return) (interface{}, error) {
+	c.line = stmt.Keyword.Line
+	if stmt.Value == nil {
+		c.emitReturn()
+		return nil, nil
+	}
+	c.compileExpr(stmt.Value)
+	c.emitOp(OpReturn)
+	return nil, nil
+}
+
+func (c *Compiler) VisitVarStmt(stmt *stmt.Var) (interface{}, error) {
+	c.line = stmt.Name.Line
+	c.declareVariable(stmt.Name)
+	if stmt.Initializer != nil {
+		c.compileExpr(stmt.Initializer)
+	} else {
+		c.emitOp(OpNil)
+	}
+	c.defineVariable(stmt.Name)
+	return nil, nil
+}
+
+func (c *Compiler) VisitWhileStmt(stmt *stmt.While) (interface{}, error) {
+	loopStart := len(c.current.function.Chunk.Code)
+	c.compileExpr(stmt.Condition)
+	exitJump := c.emitJump(OpJumpIfFalse)
+	c.emitOp(OpPop)
+	c.compileStmt(stmt.Body)
+	c.emitLoop(loopStart)
+
+	c.patchJump(exitJump)
+	c.emitOp(OpPop)
+	return nil, nil
+}
+
+func (c *Compiler) VisitAssignExpr(expr *expr.Assign) (interface{}, error) {
+	c.namedVariable(expr.Name, expr.Value)
+	return nil, nil
+}
+
+func (c *Compiler) VisitBinaryExpr(expr *expr.Binary) (interface{}, error) {
+	c.compileExpr(expr.Left)
+	c.compileExpr(expr.Right)
+
+	c.line = expr.Operator.Line
+	switch expr.Operator.Type {
+	case token.BANG_EQUAL:
+		c.emitOp(OpEqual)
+		c.emitOp(OpNot)
+	case token.EQUAL_EQUAL:
+		c.emitOp(OpEqual)
+	case token.GREATER:
+		c.emitOp(OpGreater)
+	case token.GREATER_EQUAL:
+		c.emitOp(OpGreaterEqual)
+	case token.LESS:
+		c.emitOp(OpLess)
+	case token.LESS_EQUAL:
+		c.emitOp(OpLessEqual)
+	case token.PLUS:
+		c.emitOp(OpAdd)
+	case token.MINUS:
+		c.emitOp(OpSubtract)
+	case token.STAR:
+		c.emitOp(OpMultiply)
+	case token.SLASH:
+		c.emitOp(OpDivide)
+	}
+	return nil, nil
+}
+
+func (c *Compiler) VisitCallExpr(call *expr.Call) (interface{}, error) {
+	c.compileExpr(call.Callee)
+	for _, argument := range call.Arguments {
+		c.compileExpr(argument)
+	}
+	c.line = call.Paren.Line
+	c.emitOp(OpCall)
+	c.emitByte(byte(len(call.Arguments)))
+	return nil, nil
+}
+
+func (c *Compiler) VisitGetExpr(expr *expr.Get) (interface{}, error) {
+	c.compileExpr(expr.Object)
+	c.line = expr.Name.Line
+	c.emitOp(OpGetProperty)
+	c.emitShort(c.identifierConstant(expr.Name.Lexeme))
+	return nil, nil
+}
+
+func (c *Compiler) VisitGroupingExpr(expr *expr.Grouping) (interface{}, error) {
+	c.compileExpr(expr.Expression)
+	return nil, nil
+}
+
+func (c *Compiler) VisitIndexExpr(expr *expr.Index) (interface{}, error) {
+	c.compileExpr(expr.Object)
+	c.compileExpr(expr.Index)
+	c.line = expr.Bracket.Line
+	c.emitOp(OpGetIndex)
+	return nil, nil
+}
+
+func (c *Compiler) VisitListExpr(expr *expr.List) (interface{}, error) {
+	if len(expr.Elements) > maxElements {
+		c.errorAt(expr.Bracket, "Too many elements in list literal.")
+	}
+	for _, element := range expr.Elements {
+		c.compileExpr(element)
+	}
+	c.line = expr.Bracket.Line
+	c.emitOp(OpBuildList)
+	c.emitShort(len(expr.Elements))
+	return nil, nil
+}
+
+func (c *Compiler) VisitLiteralExpr(expr *expr.Literal) (interface{}, error) {
+	switch value := expr.Value.(type) {
+	case nil:
+		c.emitOp(OpNil)
+	case bool:
+		if value {
+			c.emitOp(OpTrue)
+		} else {
+			c.emitOp(OpFalse)
+		}
+	case float64:
+		c.emitConstant(NumberValue(value))
+	case string:
+		c.emitOp(OpConstant)
+		c.emitShort(c.identifierConstant(value))
+	}
+	return nil, nil
+}
+
+// VisitLogicalExpr leaves the operand that decided the result on the stack,
+// skipping the right operand when the left one settles it.
+func (c *Compiler) VisitLogicalExpr(expr *expr.Logical) (interface{}, error) {
+	c.compileExpr(expr.Left)
+	if expr.Operator.Type == token.AND {
+		endJump := c.emitJump(OpJumpIfFalse)
+		c.emitOp(OpPop)
+		c.compileExpr(expr.Right)
+		c.patchJump(endJump)
+		return nil, nil
+	}
+
+	elseJump := c.emitJump(OpJumpIfFalse)
+	endJump := c.emitJump(OpJump)
+	c.patchJump(elseJump)
+	c.emitOp(OpPop)
+	c.compileExpr(expr.Right)
+	c.patchJump(endJump)
+	return nil, nil
+}
+
+func (c *Compiler) VisitMapExpr(expr *expr.Map) (interface{}, error) {
+	if len(expr.Keys) > maxElements {
+		c.errorAt(expr.Brace, "Too many entries in map literal.")
+	}
+	for n, key := range expr.Keys {
+		c.compileExpr(key)
+		c.compileExpr(expr.Values[n])
+	}
+	c.line = expr.Brace.Line
+	c.emitOp(OpBuildMap)
+	c.emitShort(len(expr.Keys))
+	return nil, nil
+}
+
+func (c *Compiler) VisitSetExpr(expr *expr.Set) (interface{}, error) {
+	c.compileExpr(expr.Object)
+	c.compileExpr(expr.Value)
+	c.line = expr.Name.Line
+	c.emitOp(OpSetProperty)
+	c.emitShort(c.identifierConstant(expr.Name.Lexeme))
+	return nil, nil
+}
+
+func (c *Compiler) VisitSetIndexExpr(expr *expr.SetIndex) (interface{}, error) {
+	c.compileExpr(expr.Object)
+	c.compileExpr(expr.Index)
+	c.compileExpr(expr.Value)
+	c.line = expr.Bracket.Line
+	c.emitOp(OpSetIndex)
+	return nil, nil
+}
+
+func (c *Compiler) VisitStringifyExpr(expr *expr.Stringify) (interface{}, error) {
+	c.compileExpr(expr.Expression)
+	c.emitOp(OpStringify)
+	return nil, nil
+}
+
+func (c *Compiler) VisitSuperExpr(expr *expr.Super) (interface{}, error) {
+	this := expr.Keyword
+	this.Type, this.Lexeme = token.THIS, "this"
+	c.namedVariable(this, nil)
+	c.namedVariable(expr.Keyword, nil)
+	c.line = expr.Method.Line
+	c.emitOp(OpGetSuper)
+	c.emitShort(c.identifierConstant(expr.Method.Lexeme))
+	return nil, nil
+}
+
+func (c *Compiler) VisitThisExpr(expr *expr.This) (interface{}, error) {
+	c.namedVariable(expr.Keyword, nil)
+	return nil, nil
+}
+
+func (c *Compiler) VisitUnaryExpr(expr *expr.Unary) (interface{}, error) {
+	c.compileExpr(expr.Right)
+	c.line = expr.Operator.Line
+	switch expr.Operator.Type {
+	case token.BANG:
+		c.emitOp(OpNot)
+	case token.MINUS:
+		c.emitOp(OpNegate)
+	}
+	return nil, nil
+}
+
+func (c *Compiler) VisitVariableExpr(expr *expr.Variable) (interface{}, error) {
+	c.namedVariable(expr.Name, nil)
+	return nil, nil
+}
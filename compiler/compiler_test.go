@@ -0,0 +1,224 @@
+package compiler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/joshbochu/golox/expr"
+	"github.com/joshbochu/golox/loxerror"
+	"github.com/joshbochu/golox/parser"
+	"github.com/joshbochu/golox/resolver"
+	"github.com/joshbochu/golox/scanner"
+)
+
+// noLocals discards the resolver's results, which the compiler works out itself.
+type noLocals struct{}
+
+func (noLocals) Resolve(expr expr.Expr, depth int) {}
+
+func compile(t *testing.T, source string) (*Function, *loxerror.Collector, error) {
+	t.Helper()
+	reporter := &loxerror.Collector{}
+	tokens := scanner.NewScanner(source, reporter).ScanTokens()
+	statements, err := parser.NewParser(tokens, reporter).Parse()
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if err := resolver.NewResolver(noLocals{}, reporter).Resolve(statements); err != nil {
+		t.Fatalf("Resolve error: %v", err)
+	}
+	function, err := NewCompiler(reporter).Compile(statements)
+	return function, reporter, err
+}
+
+func op(ops ...OpCode) []byte {
+	code := make([]byte, len(ops))
+	for i, op := range ops {
+		code[i] = byte(op)
+	}
+	return code
+}
+
+func concat(parts ...[]byte) []byte {
+	var code []byte
+	for _, part := range parts {
+		code = append(code, part...)
+	}
+	return code
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    string
+		code      []byte
+		constants []Value
+	}{
+		{
+			name:   "Arithmetic",
+			source: "print 1 + 2 * 3;",
+			code: concat(
+				op(OpConstant), []byte{0, 0},
+				op(OpConstant), []byte{0, 1},
+				op(OpConstant), []byte{0, 2},
+				op(OpMultiply, OpAdd, OpPrint, OpNil, OpReturn),
+			),
+			constants: []Value{NumberValue(1), NumberValue(2), NumberValue(3)},
+		},
+		{
+			name:   "Globals share a name constant",
+			source: "var a = true; a = a;",
+			code: concat(
+				op(OpTrue, OpDefineGlobal), []byte{0, 0},
+				op(OpGetGlobal), []byte{0, 0},
+				op(OpSetGlobal), []byte{0, 0},
+				op(OpPop, OpNil, OpReturn),
+			),
+			constants: []Value{ObjectValue("a")},
+		},
+		{
+			name:   "Locals live in stack slots",
+			source: "{ var a = nil; var b = a; }",
+			code: concat(
+				op(OpNil),
+				op(OpGetLocal), []byte{1},
+				op(OpPop, OpPop, OpNil, OpReturn),
+			),
+		},
+		{
+			name:   "Not equal",
+			source: "false != nil;",
+			code:   op(OpFalse, OpNil, OpEqual, OpNot, OpPop, OpNil, OpReturn),
+		},
+		{
+			name:   "If else",
+			source: "if (true) nil; else false;",
+			code: concat(
+				op(OpTrue),
+				op(OpJumpIfFalse), []byte{0, 6},
+				op(OpPop, OpNil, OpPop),
+				op(OpJump), []byte{0, 3},
+				op(OpPop, OpFalse, OpPop),
+				op(OpNil, OpReturn),
+			),
+		},
+		{
+			name:   "While",
+			source: "while (false) nil;",
+			code: concat(
+				op(OpFalse),
+				op(OpJumpIfFalse), []byte{0, 6},
+				op(OpPop, OpNil, OpPop),
+				op(OpLoop), []byte{0, 10},
+				op(OpPop, OpNil, OpReturn),
+			),
+		},
+		{
+			name:   "Lists",
+			source: "[nil, true][0] = false;",
+			code: concat(
+				op(OpNil, OpTrue, OpBuildList), []byte{0, 2},
+				op(OpConstant), []byte{0, 0},
+				op(OpFalse, OpSetIndex, OpPop, OpNil, OpReturn),
+			),
+			constants: []Value{NumberValue(0)},
+		},
+		{
+			name:   "Maps",
+			source: "print {nil: true};",
+			code: concat(
+				op(OpNil, OpTrue, OpBuildMap), []byte{0, 1},
+				op(OpPrint, OpNil, OpReturn),
+			),
+		},
+		{
+			name:   "For-in keeps the iterator in a hidden local",
+			source: "for (var x in nil) x;",
+			code: concat(
+				op(OpNil, OpIterate),
+				op(OpForIter), []byte{0, 7},
+				op(OpGetLocal), []byte{2},
+				op(OpPop, OpPop),
+				op(OpLoop), []byte{0, 10},
+				op(OpPop, OpNil, OpReturn),
+			),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			function, _, err := compile(t, test.source)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(function.Chunk.Code, test.code) {
+				t.Errorf("Expected code %v, got %v", test.code, function.Chunk.Code)
+			}
+			if len(test.constants) > 0 && !reflect.DeepEqual(function.Chunk.Constants, test.constants) {
+				t.Errorf("Expected constants %v, got %v", test.constants, function.Chunk.Constants)
+			}
+		})
+	}
+}
+
+func TestCompileClosure(t *testing.T) {
+	function, _, err := compile(t, "fun outer() { var x = 1; fun inner() { return x; } return inner; }")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	outer := function.Chunk.Constants[0].Object.(*Function)
+	if outer.Name != "outer" || outer.Arity != 0 || outer.UpvalueCount != 0 {
+		t.Fatalf("Unexpected outer function %+v", outer)
+	}
+	inner := outer.Chunk.Constants[1].Object.(*Function)
+	if inner.UpvalueCount != 1 {
+		t.Errorf("Expected inner to capture 1 upvalue, got %d", inner.UpvalueCount)
+	}
+	// OP_CLOSURE inner, capturing local slot 1 of outer
+	closure := concat(op(OpClosure), []byte{0, 1, 1, 1})
+	if !strings.Contains(string(outer.Chunk.Code), string(closure)) {
+		t.Errorf("Expected %v in %v", closure, outer.Chunk.Code)
+	}
+	if code := inner.Chunk.Code; !reflect.DeepEqual(code[:3], concat(op(OpGetUpvalue), []byte{0}, op(OpReturn))) {
+		t.Errorf("Expected inner to return its upvalue, got %v", code)
+	}
+}
+
+func TestChunkLines(t *testing.T) {
+	function, _, err := compile(t, "var a = 1;\n\nprint a\n  - 2;")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	chunk := function.Chunk
+	expected := []LineStart{{Offset: 0, Line: 1}, {Offset: 6, Line: 3}, {Offset: 12, Line: 4}}
+	if !reflect.DeepEqual(chunk.Lines, expected) {
+		t.Fatalf("Expected line table %v, got %v", expected, chunk.Lines)
+	}
+	for offset, line := range map[int]int{0: 1, 5: 1, 6: 3, 11: 3, 12: 4, len(chunk.Code) - 1: 4} {
+		if got := chunk.Line(offset); got != line {
+			t.Errorf("Expected offset %d on line %d, got %d", offset, line, got)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	var locals strings.Builder
+	locals.WriteString("{\n")
+	for i := 0; i < 256; i++ {
+		locals.WriteString("var v" + strings.Repeat("x", i) + ";\n")
+	}
+	locals.WriteString("}")
+
+	_, reporter, err := compile(t, locals.String())
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if len(reporter.Diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", reporter.Diagnostics)
+	}
+	d := reporter.Diagnostics[0]
+	if d.Message != "Too many local variables in function." || d.Line != 257 || d.Code != loxerror.CodeCompile {
+		t.Errorf("Unexpected diagnostic %+v", d)
+	}
+}
@@ -0,0 +1,111 @@
+package compiler
+
+import (
+	"fmt"
+	"io"
+)
+
+// Disassemble writes a listing of function's chunk to w, followed by the
+// listings of the functions it declares.
+func Disassemble(w io.Writer, function *Function) {
+	name := function.Name
+	if name == "" {
+		name = "<script>"
+	}
+	DisassembleChunk(w, function.Chunk, name)
+	for _, constant := range function.Chunk.Constants {
+		if nested, ok := constant.Object.(*Function); ok {
+			fmt.Fprintln(w)
+			Disassemble(w, nested)
+		}
+	}
+}
+
+// DisassembleChunk writes a listing of every instruction in chunk under a
+// header naming it.
+func DisassembleChunk(w io.Writer, chunk *Chunk, name string) {
+	fmt.Fprintf(w, "== %s ==\n", name)
+	for offset := 0; offset < len(chunk.Code); {
+		offset = DisassembleInstruction(w, chunk, offset)
+	}
+}
+
+// DisassembleInstruction writes the instruction at offset on one line, as
+// its offset, source line, opcode, and operands, and returns the offset of
+// the next instruction. The line is shown as "|" when it is the same as the
+// previous instruction's.
+func DisassembleInstruction(w io.Writer, chunk *Chunk, offset int) int {
+	fmt.Fprintf(w, "%04d ", offset)
+	if offset > 0 && chunk.Line(offset) == chunk.Line(offset-1) {
+		fmt.Fprint(w, "   | ")
+	} else {
+		fmt.Fprintf(w, "%4d ", chunk.Line(offset))
+	}
+
+	switch op := OpCode(chunk.Code[offset]); op {
+	case OpConstant, OpGetGlobal, OpDefineGlobal, OpSetGlobal, OpGetProperty, OpSetProperty,
+		OpGetSuper, OpClass, OpMethod:
+		return constantInstruction(w, op, chunk, offset)
+	case OpGetLocal, OpSetLocal, OpGetUpvalue, OpSetUpvalue, OpCall:
+		return byteInstruction(w, op, chunk, offset)
+	case OpBuildList, OpBuildMap:
+		return shortInstruction(w, op, chunk, offset)
+	case OpJump, OpJumpIfFalse, OpForIter:
+		return jumpInstruction(w, op, 1, chunk, offset)
+	case OpLoop:
+		return jumpInstruction(w, op, -1, chunk, offset)
+	case OpClosure:
+		return closureInstruction(w, chunk, offset)
+	case OpNil, OpTrue, OpFalse, OpPop, OpEqual, OpGreater, OpGreaterEqual, OpLess, OpLessEqual,
+		OpGetIndex, OpSetIndex, OpIterate, OpAdd, OpSubtract, OpMultiply, OpDivide, OpNot, OpNegate, OpStringify, OpPrint,
+		OpCloseUpvalue, OpReturn, OpInherit:
+		fmt.Fprintln(w, op)
+		return offset + 1
+	default:
+		fmt.Fprintf(w, "Unknown opcode %d\n", op)
+		return offset + 1
+	}
+}
+
+func constantInstruction(w io.Writer, op OpCode, chunk *Chunk, offset int) int {
+	constant := chunk.ReadShort(offset + 1)
+	fmt.Fprintf(w, "%-16s %4d '%s'\n", op, constant, chunk.Constants[constant])
+	return offset + 3
+}
+
+func byteInstruction(w io.Writer, op OpCode, chunk *Chunk, offset int) int {
+	fmt.Fprintf(w, "%-16s %4d\n", op, chunk.Code[offset+1])
+	return offset + 2
+}
+
+func shortInstruction(w io.Writer, op OpCode, chunk *Chunk, offset int) int {
+	fmt.Fprintf(w, "%-16s %4d\n", op, chunk.ReadShort(offset+1))
+	return offset + 3
+}
+
+// jumpInstruction shows a jump's offset along with where it lands; sign is
+// -1 for jumps backwards.
+func jumpInstruction(w io.Writer, op OpCode, sign int, chunk *Chunk, offset int) int {
+	jump := chunk.ReadShort(offset + 1)
+	fmt.Fprintf(w, "%-16s %4d -> %d\n", op, offset, offset+3+sign*jump)
+	return offset + 3
+}
+
+// closureInstruction shows the function constant followed by a line for
+// each variable the closure captures.
+func closureInstruction(w io.Writer, chunk *Chunk, offset int) int {
+	constant := chunk.ReadShort(offset + 1)
+	fmt.Fprintf(w, "%-16s %4d %s\n", OpClosure, constant, chunk.Constants[constant])
+	offset += 3
+
+	function := chunk.Constants[constant].Object.(*Function)
+	for i := 0; i < function.UpvalueCount; i++ {
+		kind := "upvalue"
+		if chunk.Code[offset] == 1 {
+			kind = "local"
+		}
+		fmt.Fprintf(w, "%04d      |                     %s %d\n", offset, kind, chunk.Code[offset+1])
+		offset += 2
+	}
+	return offset
+}
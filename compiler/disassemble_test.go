@@ -0,0 +1,86 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDisassemble(t *testing.T) {
+	source := `var greeting = "hi";
+fun greet(name) {
+  var n = 0;
+  fun inner() { return name; }
+  while (n < 2) n = n + 1;
+  print greeting + name;
+}
+greet("bob");`
+	expected := `== <script> ==
+0000    1 OP_CONSTANT         0 'hi'
+0003    | OP_DEFINE_GLOBAL    1 'greeting'
+0006    2 OP_CLOSURE          2 <fn greet>
+0009    | OP_DEFINE_GLOBAL    3 'greet'
+0012    8 OP_GET_GLOBAL       3 'greet'
+0015    | OP_CONSTANT         4 'bob'
+0018    | OP_CALL             1
+0020    | OP_POP
+0021    | OP_NIL
+0022    | OP_RETURN
+
+== greet ==
+0000    3 OP_CONSTANT         0 '0'
+0003    4 OP_CLOSURE          1 <fn inner>
+0006      |                     local 1
+0008    5 OP_GET_LOCAL        2
+0010    | OP_CONSTANT         2 '2'
+0013    | OP_LESS
+0014    | OP_JUMP_IF_FALSE   14 -> 30
+0017    | OP_POP
+0018    | OP_GET_LOCAL        2
+0020    | OP_CONSTANT         3 '1'
+0023    | OP_ADD
+0024    | OP_SET_LOCAL        2
+0026    | OP_POP
+0027    | OP_LOOP            27 -> 8
+0030    | OP_POP
+0031    6 OP_GET_GLOBAL       4 'greeting'
+0034    | OP_GET_LOCAL        1
+0036    | OP_ADD
+0037    | OP_PRINT
+0038    | OP_NIL
+0039    | OP_RETURN
+
+== inner ==
+0000    4 OP_GET_UPVALUE      0
+0002    | OP_RETURN
+0003    | OP_NIL
+0004    | OP_RETURN
+`
+
+	function, _, err := compile(t, source)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var out strings.Builder
+	Disassemble(&out, function)
+	if out.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestDisassembleInstruction(t *testing.T) {
+	chunk := &Chunk{}
+	chunk.Write(byte(OpNil), 1)
+	chunk.Write(byte(OpPop), 1)
+	chunk.Write(255, 2)
+
+	var out strings.Builder
+	offset := DisassembleInstruction(&out, chunk, 1)
+	offset = DisassembleInstruction(&out, chunk, offset)
+	if offset != 3 {
+		t.Errorf("Expected to end at offset 3, got %d", offset)
+	}
+	expected := "0001    | OP_POP\n0002    2 Unknown opcode 255\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
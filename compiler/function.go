@@ -0,0 +1,21 @@
+package compiler
+
+// Function is a compiled function body. The top-level script is compiled
+// into a Function with an empty name.
+type Function struct {
+	Name         string
+	Arity        int
+	UpvalueCount int
+	Chunk        *Chunk
+}
+
+func NewFunction(name string) *Function {
+	return &Function{Name: name, Chunk: &Chunk{}}
+}
+
+func (f *Function) String() string {
+	if f.Name == "" {
+		return "<script>"
+	}
+	return "<fn " + f.Name + ">"
+}
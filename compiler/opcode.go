@@ -0,0 +1,122 @@
+package compiler
+
+// OpCode is a single bytecode instruction. Operands follow it in the chunk:
+// constant and name indexes take two bytes, big endian, as do jump offsets
+// and element counts; local slots, upvalue indexes, and argument counts take
+// one.
+type OpCode byte
+
+const (
+	// OpConstant pushes the constant at its 16-bit index.
+	OpConstant OpCode = iota
+	OpNil
+	OpTrue
+	OpFalse
+	OpPop
+	OpGetLocal
+	OpSetLocal
+	OpGetGlobal
+	OpDefineGlobal
+	OpSetGlobal
+	OpGetUpvalue
+	OpSetUpvalue
+	OpGetProperty
+	OpSetProperty
+	OpGetSuper
+	// OpBuildList replaces the number of values given by its 16-bit operand
+	// with a list holding them.
+	OpBuildList
+	// OpBuildMap replaces the number of key-value pairs given by its 16-bit
+	// operand, each pushed key first, with a map holding them.
+	OpBuildMap
+	OpGetIndex
+	OpSetIndex
+	// OpIterate replaces the value on top of the stack with an iterator over
+	// it, for a for-in loop.
+	OpIterate
+	// OpForIter pushes the next value of the iterator on top of the stack, or
+	// jumps forward by its operand once the iterator is exhausted.
+	OpForIter
+	OpEqual
+	OpGreater
+	OpGreaterEqual
+	OpLess
+	OpLessEqual
+	OpAdd
+	OpSubtract
+	OpMultiply
+	OpDivide
+	OpNot
+	OpNegate
+	// OpStringify converts the top of the stack to a string, for
+	// interpolation.
+	OpStringify
+	OpPrint
+	OpJump
+	OpJumpIfFalse
+	OpLoop
+	OpCall
+	// OpClosure wraps the function constant at its 16-bit index in a
+	// closure. It is followed by an (isLocal, index) byte pair for each of
+	// the function's upvalues.
+	OpClosure
+	OpCloseUpvalue
+	OpReturn
+	OpClass
+	OpInherit
+	OpMethod
+)
+
+var opNames = [...]string{
+	OpConstant:     "OP_CONSTANT",
+	OpNil:          "OP_NIL",
+	OpTrue:         "OP_TRUE",
+	OpFalse:        "OP_FALSE",
+	OpPop:          "OP_POP",
+	OpGetLocal:     "OP_GET_LOCAL",
+	OpSetLocal:     "OP_SET_LOCAL",
+	OpGetGlobal:    "OP_GET_GLOBAL",
+	OpDefineGlobal: "OP_DEFINE_GLOBAL",
+	OpSetGlobal:    "OP_SET_GLOBAL",
+	OpGetUpvalue:   "OP_GET_UPVALUE",
+	OpSetUpvalue:   "OP_SET_UPVALUE",
+	OpGetProperty:  "OP_GET_PROPERTY",
+	OpSetProperty:  "OP_SET_PROPERTY",
+	OpGetSuper:     "OP_GET_SUPER",
+	OpBuildList:    "OP_BUILD_LIST",
+	OpBuildMap:     "OP_BUILD_MAP",
+	OpGetIndex:     "OP_GET_INDEX",
+	OpSetIndex:     "OP_SET_INDEX",
+	OpIterate:      "OP_ITERATE",
+	OpForIter:      "OP_FOR_ITER",
+	OpEqual:        "OP_EQUAL",
+	OpGreater:      "OP_GREATER",
+	OpGreaterEqual: "OP_GREATER_EQUAL",
+	OpLess:         "OP_LESS",
+	OpLessEqual:    "OP_LESS_EQUAL",
+	OpAdd:          "OP_ADD",
+	OpSubtract:     "OP_SUBTRACT",
+	OpMultiply:     "OP_MULTIPLY",
+	OpDivide:       "OP_DIVIDE",
+	OpNot:          "OP_NOT",
+	OpNegate:       "OP_NEGATE",
+	OpStringify:    "OP_STRINGIFY",
+	OpPrint:        "OP_PRINT",
+	OpJump:         "OP_JUMP",
+	OpJumpIfFalse:  "OP_JUMP_IF_FALSE",
+	OpLoop:         "OP_LOOP",
+	OpCall:         "OP_CALL",
+	OpClosure:      "OP_CLOSURE",
+	OpCloseUpvalue: "OP_CLOSE_UPVALUE",
+	OpReturn:       "OP_RETURN",
+	OpClass:        "OP_CLASS",
+	OpInherit:      "OP_INHERIT",
+	OpMethod:       "OP_METHOD",
+}
+
+func (op OpCode) String() string {
+	if int(op) < len(opNames) {
+		return opNames[op]
+	}
+	return "OP_UNKNOWN"
+}
@@ -0,0 +1,251 @@
+package compiler
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// A compiled file (.loxc) is laid out as
+//
+//	magic     "LOXC"
+//	version   uint16, big endian
+//	functions uvarint count, then each function
+//	checksum  CRC-32 (IEEE) of everything before it, uint32, big endian
+//
+// and each function as
+//
+//	name       string
+//	arity      uvarint
+//	upvalues   uvarint
+//	code       uvarint length, then the bytes
+//	lines      uvarint count, then an (offset, line) uvarint pair per entry
+//	constants  uvarint count, then a tag byte and payload per constant
+//
+// Strings are a uvarint length followed by the bytes. Numbers are their
+// IEEE 754 bits as a big endian uint64. A function constant is the uvarint
+// index of the function in the file; the script is always function zero.
+
+// FormatVersion must change whenever the instruction set or the layout of
+// compiled files does, so stale files are rejected rather than misread.
+const FormatVersion = 2
+
+const magic = "LOXC"
+
+// constant tags
+const (
+	tagNumber byte = iota + 1
+	tagString
+	tagFunction
+)
+
+var (
+	ErrNotCompiled = errors.New("not a compiled Lox file")
+	ErrCorrupt     = errors.New("compiled Lox file is corrupt")
+)
+
+// VersionError is returned when decoding a file written for a different
+// FormatVersion.
+type VersionError struct {
+	Version int
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("compiled for bytecode version %d, but this lox runs version %d; recompile it with 'lox compile'", e.Version, FormatVersion)
+}
+
+// Encode writes script, and every function declared inside it, to w in the
+// compiled file format.
+func Encode(w io.Writer, script *Function) error {
+	functions := []*Function{}
+	indexes := make(map[*Function]int)
+	var collect func(function *Function)
+	collect = func(function *Function) {
+		indexes[function] = len(functions)
+		functions = append(functions, function)
+		for _, constant := range function.Chunk.Constants {
+			if nested, ok := constant.Object.(*Function); ok {
+				collect(nested)
+			}
+		}
+	}
+	collect(script)
+
+	data := append([]byte(magic), 0, 0)
+	binary.BigEndian.PutUint16(data[len(magic):], FormatVersion)
+	data = binary.AppendUvarint(data, uint64(len(functions)))
+	for _, function := range functions {
+		data = appendString(data, function.Name)
+		data = binary.AppendUvarint(data, uint64(function.Arity))
+		data = binary.AppendUvarint(data, uint64(function.UpvalueCount))
+
+		chunk := function.Chunk
+		data = binary.AppendUvarint(data, uint64(len(chunk.Code)))
+		data = append(data, chunk.Code...)
+		data = binary.AppendUvarint(data, uint64(len(chunk.Lines)))
+		for _, line := range chunk.Lines {
+			data = binary.AppendUvarint(data, uint64(line.Offset))
+			data = binary.AppendUvarint(data, uint64(line.Line))
+		}
+
+		data = binary.AppendUvarint(data, uint64(len(chunk.Constants)))
+		for _, constant := range chunk.Constants {
+			switch value := constant.Object.(type) {
+			case string:
+				data = append(data, tagString)
+				data = appendString(data, value)
+			case *Function:
+				data = append(data, tagFunction)
+				data = binary.AppendUvarint(data, uint64(indexes[value]))
+			default:
+				if constant.Kind != KindNumber {
+					return fmt.Errorf("can't encode constant %v", constant)
+				}
+				data = append(data, tagNumber)
+				data = binary.BigEndian.AppendUint64(data, math.Float64bits(constant.Number))
+			}
+		}
+	}
+	data = binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(data))
+
+	_, err := w.Write(data)
+	return err
+}
+
+func appendString(data []byte, s string) []byte {
+	data = binary.AppendUvarint(data, uint64(len(s)))
+	return append(data, s...)
+}
+
+// Decode reads a compiled file written by Encode and returns its script.
+func Decode(r io.Reader) (*Function, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	header := len(magic) + 2
+	if len(data) < header || string(data[:len(magic)]) != magic {
+		return nil, ErrNotCompiled
+	}
+	if version := int(binary.BigEndian.Uint16(data[len(magic):])); version != FormatVersion {
+		return nil, &VersionError{Version: version}
+	}
+	if len(data) < header+4 {
+		return nil, ErrCorrupt
+	}
+	body, checksum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != checksum {
+		return nil, ErrCorrupt
+	}
+
+	d := &decoder{data: body[header:]}
+	functions := make([]*Function, d.count())
+	for i := range functions {
+		functions[i] = NewFunction("")
+	}
+	// function constants are patched once every function exists
+	var references []*Value
+	var targets []int
+	for _, function := range functions {
+		function.Name = d.string()
+		function.Arity = d.uvarint()
+		function.UpvalueCount = d.uvarint()
+
+		chunk := function.Chunk
+		chunk.Code = d.bytes(d.count())
+		chunk.Lines = make([]LineStart, d.count())
+		for i := range chunk.Lines {
+			chunk.Lines[i] = LineStart{Offset: d.uvarint(), Line: d.uvarint()}
+		}
+
+		chunk.Constants = make([]Value, d.count())
+		for i := range chunk.Constants {
+			switch d.byte() {
+			case tagNumber:
+				chunk.Constants[i] = NumberValue(math.Float64frombits(binary.BigEndian.Uint64(d.bytes(8))))
+			case tagString:
+				chunk.Constants[i] = ObjectValue(d.string())
+			case tagFunction:
+				references = append(references, &chunk.Constants[i])
+				targets = append(targets, d.uvarint())
+			default:
+				d.err = ErrCorrupt
+			}
+		}
+	}
+	if d.err != nil || len(d.data) != d.pos || len(functions) == 0 {
+		return nil, ErrCorrupt
+	}
+	for i, reference := range references {
+		if targets[i] >= len(functions) {
+			return nil, ErrCorrupt
+		}
+		*reference = ObjectValue(functions[targets[i]])
+	}
+	// the checksum only catches accidents, so check the code is safe to run
+	for _, function := range functions {
+		if !verify(function) {
+			return nil, ErrCorrupt
+		}
+	}
+	return functions[0], nil
+}
+
+// decoder reads values from data, remembering the first error so callers
+// can check once at the end. After an error every read returns zero.
+type decoder struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (d *decoder) uvarint() int {
+	if d.err != nil {
+		return 0
+	}
+	n, size := binary.Uvarint(d.data[d.pos:])
+	if size <= 0 || n > math.MaxInt32 {
+		d.err = ErrCorrupt
+		return 0
+	}
+	d.pos += size
+	return int(n)
+}
+
+// count reads a length, which can't exceed the bytes left since every item
+// takes at least one byte.
+func (d *decoder) count() int {
+	n := d.uvarint()
+	if n > len(d.data)-d.pos {
+		d.err = ErrCorrupt
+		return 0
+	}
+	return n
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > len(d.data)-d.pos {
+		d.err = ErrCorrupt
+		return nil
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+func (d *decoder) byte() byte {
+	if b := d.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes(d.count()))
+}
@@ -0,0 +1,130 @@
+package compiler
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func encode(t *testing.T, source string) []byte {
+	t.Helper()
+	function, _, err := compile(t, source)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, function); err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestEncodeDecode(t *testing.T) {
+	source := `var pi = 3.14159;
+class A { init(x) { this.x = x; } get() { return this.x; } }
+class B < A { get() { return "B" + super.get(); } }
+fun counter() {
+  var n = 0;
+  fun inc() { n = n + 1; return n; }
+  return inc;
+}
+print B("!").get() + "${counter()()}";`
+	function, _, err := compile(t, source)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, function); err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	var expected, got strings.Builder
+	Disassemble(&expected, function)
+	Disassemble(&got, decoded)
+	if got.String() != expected.String() {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected.String(), got.String())
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	valid := encode(t, `fun f(a) { return a; } print f("x");`)
+	modify := func(change func(data []byte) []byte) []byte {
+		return change(append([]byte(nil), valid...))
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"Empty", []byte{}, ErrNotCompiled},
+		{"Source file", []byte("print 1;"), ErrNotCompiled},
+		{"Flipped byte", modify(func(data []byte) []byte { data[10] ^= 0xff; return data }), ErrCorrupt},
+		{"Truncated", valid[:len(valid)-1], ErrCorrupt},
+		{"Header only", valid[:6], ErrCorrupt},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Decode(bytes.NewReader(test.data)); !errors.Is(err, test.err) {
+				t.Errorf("Expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestDecodeVersionMismatch(t *testing.T) {
+	data := encode(t, "print 1;")
+	data[5] = FormatVersion + 1
+
+	_, err := Decode(bytes.NewReader(data))
+	var versionErr *VersionError
+	if !errors.As(err, &versionErr) || versionErr.Version != FormatVersion+1 {
+		t.Fatalf("Expected a version error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "recompile") {
+		t.Errorf("Expected the error to say how to fix it, got %q", err.Error())
+	}
+}
+
+func TestDecodeRejectsUnsafeCode(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      []byte
+		constants []Value
+	}{
+		{"Unknown opcode", []byte{0xff}, nil},
+		{"Missing operand", concat(op(OpConstant), []byte{0}), []Value{NumberValue(1)}},
+		{"Constant out of range", concat(op(OpConstant), []byte{0, 1}, op(OpReturn)), []Value{NumberValue(1)}},
+		{"Name isn't a string", concat(op(OpGetGlobal), []byte{0, 0}, op(OpReturn)), []Value{NumberValue(1)}},
+		{"Jump past the end", concat(op(OpJump), []byte{0, 9}, op(OpNil, OpReturn)), nil},
+		{"Jump into an operand", concat(op(OpLoop), []byte{0, 2}, op(OpNil, OpReturn)), nil},
+		{"Local slot out of range", concat(op(OpGetLocal), []byte{1}, op(OpReturn)), nil},
+		{"Upvalue out of range", concat(op(OpGetUpvalue), []byte{0}, op(OpReturn)), nil},
+		{"Stack underflow", op(OpAdd, OpReturn), nil},
+		{"Runs off the end", op(OpNil), nil},
+		{
+			"Mismatched stack at a join",
+			concat(op(OpTrue), op(OpJumpIfFalse), []byte{0, 1}, op(OpNil, OpReturn)),
+			nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			function := NewFunction("")
+			function.Chunk.Code = test.code
+			function.Chunk.Constants = test.constants
+			var buf bytes.Buffer
+			if err := Encode(&buf, function); err != nil {
+				t.Fatalf("Encode error: %v", err)
+			}
+			if _, err := Decode(&buf); !errors.Is(err, ErrCorrupt) {
+				t.Errorf("Expected %v, got %v", ErrCorrupt, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package compiler
+
+import "fmt"
+
+type ValueKind byte
+
+const (
+	KindNil ValueKind = iota
+	KindBool
+	KindNumber
+	// KindObject covers strings, functions, and the VM's runtime objects.
+	KindObject
+)
+
+// Value is a Lox value in the bytecode backend. Booleans and numbers are
+// stored inline instead of being boxed in an interface{}.
+type Value struct {
+	Kind   ValueKind
+	Bool   bool
+	Number float64
+	Object interface{}
+}
+
+var Nil = Value{Kind: KindNil}
+
+func BoolValue(b bool) Value {
+	return Value{Kind: KindBool, Bool: b}
+}
+
+func NumberValue(n float64) Value {
+	return Value{Kind: KindNumber, Number: n}
+}
+
+func ObjectValue(object interface{}) Value {
+	return Value{Kind: KindObject, Object: object}
+}
+
+// IsFalsey follows Lox's rule that only nil and false are falsey.
+func (v Value) IsFalsey() bool {
+	return v.Kind == KindNil || (v.Kind == KindBool && !v.Bool)
+}
+
+// AsString returns the value's string, if it is one.
+func (v Value) AsString() (string, bool) {
+	s, ok := v.Object.(string)
+	return s, ok && v.Kind == KindObject
+}
+
+// Equal compares values the way the tree-walking interpreter does: numbers,
+// booleans, and strings by value, everything else by identity.
+func (v Value) Equal(other Value) bool {
+	if v.Kind != other.Kind {
+		return false
+	}
+	switch v.Kind {
+	case KindNil:
+		return true
+	case KindBool:
+		return v.Bool == other.Bool
+	case KindNumber:
+		return v.Number == other.Number
+	default:
+		return v.Object == other.Object
+	}
+}
+
+// String formats the value as print does.
+func (v Value) String() string {
+	switch v.Kind {
+	case KindNil:
+		return "nil"
+	case KindBool:
+		return fmt.Sprintf("%v", v.Bool)
+	case KindNumber:
+		return fmt.Sprintf("%v", v.Number)
+	default:
+		return fmt.Sprintf("%v", v.Object)
+	}
+}
@@ -0,0 +1,169 @@
+package compiler
+
+// verify reports whether function's code is safe to hand to the VM: every
+// opcode is known, operands fit in the code, constant indexes refer to
+// constants of the right kind, jumps land on instructions, and no
+// instruction pops more than the stack holds or reaches a local slot or
+// upvalue that doesn't exist. It follows every path through the code, so a
+// join point has to be reached with the same stack height from each side,
+// as the compiler always arranges. It doesn't check the types of values on
+// the stack, which the VM does as it runs.
+func verify(function *Function) bool {
+	chunk := function.Chunk
+	code, constants := chunk.Code, chunk.Constants
+	// heights[offset] is the stack height on reaching the instruction at
+	// offset, counted from the frame's slot zero, or -1 if not yet reached
+	heights := make([]int, len(code))
+	for i := range heights {
+		heights[i] = -1
+	}
+	if len(code) == 0 {
+		return false
+	}
+	pending := []int{0}
+	heights[0] = 1 + function.Arity
+	reach := func(offset int, height int) bool {
+		if offset < 0 || offset >= len(code) {
+			return false
+		}
+		if heights[offset] == -1 {
+			heights[offset] = height
+			pending = append(pending, offset)
+			return true
+		}
+		return heights[offset] == height
+	}
+
+	for len(pending) > 0 {
+		offset := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		height := heights[offset]
+		op := OpCode(code[offset])
+		width, ok := operandWidth(op, chunk, offset)
+		if !ok || offset+1+width > len(code) {
+			return false
+		}
+		next := offset + 1 + width
+
+		pops, pushes := 0, 0
+		switch op {
+		case OpConstant:
+			if chunk.ReadShort(offset+1) >= len(constants) {
+				return false
+			}
+			pushes = 1
+		case OpNil, OpTrue, OpFalse:
+			pushes = 1
+		case OpPop, OpPrint, OpCloseUpvalue, OpDefineGlobal:
+			pops = 1
+		case OpGetLocal, OpSetLocal:
+			if int(code[offset+1]) >= height {
+				return false
+			}
+			if op == OpGetLocal {
+				pushes = 1
+			} else {
+				pops, pushes = 1, 1
+			}
+		case OpGetUpvalue, OpSetUpvalue:
+			if int(code[offset+1]) >= function.UpvalueCount {
+				return false
+			}
+			if op == OpGetUpvalue {
+				pushes = 1
+			} else {
+				pops, pushes = 1, 1
+			}
+		case OpGetGlobal, OpClass:
+			pushes = 1
+		case OpSetGlobal, OpGetProperty:
+			pops, pushes = 1, 1
+		case OpSetProperty, OpGetSuper, OpInherit, OpMethod:
+			pops, pushes = 2, 1
+		case OpBuildList:
+			pops, pushes = chunk.ReadShort(offset+1), 1
+		case OpBuildMap:
+			pops, pushes = 2*chunk.ReadShort(offset+1), 1
+		case OpGetIndex, OpEqual, OpGreater, OpGreaterEqual, OpLess, OpLessEqual,
+			OpAdd, OpSubtract, OpMultiply, OpDivide:
+			pops, pushes = 2, 1
+		case OpSetIndex:
+			pops, pushes = 3, 1
+		case OpIterate, OpNot, OpNegate, OpStringify:
+			pops, pushes = 1, 1
+		case OpCall:
+			pops, pushes = int(code[offset+1])+1, 1
+		case OpClosure:
+			nested := constants[chunk.ReadShort(offset+1)].Object.(*Function)
+			for i := 0; i < nested.UpvalueCount; i++ {
+				isLocal, index := code[offset+3+2*i], int(code[offset+4+2*i])
+				if isLocal > 1 || (isLocal == 1 && index >= height) || (isLocal == 0 && index >= function.UpvalueCount) {
+					return false
+				}
+			}
+			pushes = 1
+		case OpJump:
+			if !reach(next+chunk.ReadShort(offset+1), height) {
+				return false
+			}
+			continue
+		case OpLoop:
+			if !reach(next-chunk.ReadShort(offset+1), height) {
+				return false
+			}
+			continue
+		case OpJumpIfFalse:
+			if height < 1 || !reach(next+chunk.ReadShort(offset+1), height) {
+				return false
+			}
+		case OpForIter:
+			if height < 1 || !reach(next+chunk.ReadShort(offset+1), height) {
+				return false
+			}
+			pops, pushes = 1, 2
+		case OpReturn:
+			if height < 1 {
+				return false
+			}
+			continue
+		}
+		if height < pops || !reach(next, height-pops+pushes) {
+			return false
+		}
+	}
+	return true
+}
+
+// operandWidth returns how many bytes of operands follow the instruction at
+// offset, checking the constants that decide it: names must be strings and
+// a closure's function says how many upvalue pairs follow.
+func operandWidth(op OpCode, chunk *Chunk, offset int) (int, bool) {
+	switch op {
+	case OpConstant, OpBuildList, OpBuildMap, OpJump, OpJumpIfFalse, OpLoop, OpForIter:
+		return 2, true
+	case OpGetLocal, OpSetLocal, OpGetUpvalue, OpSetUpvalue, OpCall:
+		return 1, true
+	case OpNil, OpTrue, OpFalse, OpPop, OpGetIndex, OpSetIndex, OpIterate, OpEqual, OpGreater,
+		OpGreaterEqual, OpLess, OpLessEqual, OpAdd, OpSubtract, OpMultiply, OpDivide, OpNot,
+		OpNegate, OpStringify, OpPrint, OpCloseUpvalue, OpReturn, OpInherit:
+		return 0, true
+	}
+
+	if offset+3 > len(chunk.Code) || chunk.ReadShort(offset+1) >= len(chunk.Constants) {
+		return 0, false
+	}
+	constant := chunk.Constants[chunk.ReadShort(offset+1)]
+	switch op {
+	case OpGetGlobal, OpDefineGlobal, OpSetGlobal, OpGetProperty, OpSetProperty, OpGetSuper,
+		OpClass, OpMethod:
+		_, ok := constant.AsString()
+		return 2, ok
+	case OpClosure:
+		nested, ok := constant.Object.(*Function)
+		if !ok {
+			return 0, false
+		}
+		return 2 + 2*nested.UpvalueCount, true
+	}
+	return 0, false
+}
@@ -0,0 +1,11 @@
+package expr
+
+// Binding is where the resolver found the declaration of the variable an
+// expression refers to. It lives on the node rather than in a side table so
+// it is freed along with the rest of the tree. The zero Binding is a global.
+type Binding struct {
+	// Local is set when the declaration is in an enclosing scope.
+	Local bool
+	// Depth counts the scopes between the use and the declaration.
+	Depth int
+}
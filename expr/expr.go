@@ -9,13 +9,39 @@ type Expr interface {
 }
 
 type ExprVisitor interface {
+	VisitAssignExpr(expr *Assign) (interface{}, error)
 	VisitBinaryExpr(expr *Binary) (interface{}, error)
+	VisitCallExpr(expr *Call) (interface{}, error)
+	VisitGetExpr(expr *Get) (interface{}, error)
 	VisitGroupingExpr(expr *Grouping) (interface{}, error)
+	VisitIndexExpr(expr *Index) (interface{}, error)
+	VisitListExpr(expr *List) (interface{}, error)
 	VisitLiteralExpr(expr *Literal) (interface{}, error)
+	VisitLogicalExpr(expr *Logical) (interface{}, error)
+	VisitMapExpr(expr *Map) (interface{}, error)
+	VisitSetExpr(expr *Set) (interface{}, error)
+	VisitSetIndexExpr(expr *SetIndex) (interface{}, error)
+	VisitStringifyExpr(expr *Stringify) (interface{}, error)
+	VisitSuperExpr(expr *Super) (interface{}, error)
+	VisitThisExpr(expr *This) (interface{}, error)
 	VisitUnaryExpr(expr *Unary) (interface{}, error)
 	VisitVariableExpr(expr *Variable) (interface{}, error)
 }
 
+type Assign struct {
+	Name    token.Token
+	Value   Expr
+	Binding Binding
+}
+
+func (e *Assign) Accept(visitor ExprVisitor) (interface{}, error) {
+	val, err := visitor.VisitAssignExpr(e)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
 type Binary struct {
 	Left     Expr
 	Operator token.Token
@@ -30,6 +56,33 @@ func (e *Binary) Accept(visitor ExprVisitor) (interface{}, error) {
 	return val, nil
 }
 
+type Call struct {
+	Callee    Expr
+	Paren     token.Token
+	Arguments []Expr
+}
+
+func (e *Call) Accept(visitor ExprVisitor) (interface{}, error) {
+	val, err := visitor.VisitCallExpr(e)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+type Get struct {
+	Object Expr
+	Name   token.Token
+}
+
+func (e *Get) Accept(visitor ExprVisitor) (interface{}, error) {
+	val, err := visitor.VisitGetExpr(e)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
 type Grouping struct {
 	Expression Expr
 }
@@ -42,6 +95,33 @@ func (e *Grouping) Accept(visitor ExprVisitor) (interface{}, error) {
 	return val, nil
 }
 
+type Index struct {
+	Object  Expr
+	Bracket token.Token
+	Index   Expr
+}
+
+func (e *Index) Accept(visitor ExprVisitor) (interface{}, error) {
+	val, err := visitor.VisitIndexExpr(e)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+type List struct {
+	Bracket  token.Token
+	Elements []Expr
+}
+
+func (e *List) Accept(visitor ExprVisitor) (interface{}, error) {
+	val, err := visitor.VisitListExpr(e)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
 type Literal struct {
 	Value interface{}
 }
@@ -54,6 +134,102 @@ func (e *Literal) Accept(visitor ExprVisitor) (interface{}, error) {
 	return val, nil
 }
 
+type Logical struct {
+	Left     Expr
+	Operator token.Token
+	Right    Expr
+}
+
+func (e *Logical) Accept(visitor ExprVisitor) (interface{}, error) {
+	val, err := visitor.VisitLogicalExpr(e)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+type Map struct {
+	Brace  token.Token
+	Keys   []Expr
+	Values []Expr
+}
+
+func (e *Map) Accept(visitor ExprVisitor) (interface{}, error) {
+	val, err := visitor.VisitMapExpr(e)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+type Set struct {
+	Object Expr
+	Name   token.Token
+	Value  Expr
+}
+
+func (e *Set) Accept(visitor ExprVisitor) (interface{}, error) {
+	val, err := visitor.VisitSetExpr(e)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+type SetIndex struct {
+	Object  Expr
+	Bracket token.Token
+	Index   Expr
+	Value   Expr
+}
+
+func (e *SetIndex) Accept(visitor ExprVisitor) (interface{}, error) {
+	val, err := visitor.VisitSetIndexExpr(e)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+type Stringify struct {
+	Expression Expr
+}
+
+func (e *Stringify) Accept(visitor ExprVisitor) (interface{}, error) {
+	val, err := visitor.VisitStringifyExpr(e)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+type Super struct {
+	Keyword token.Token
+	Method  token.Token
+	Binding Binding
+}
+
+func (e *Super) Accept(visitor ExprVisitor) (interface{}, error) {
+	val, err := visitor.VisitSuperExpr(e)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+type This struct {
+	Keyword token.Token
+	Binding Binding
+}
+
+func (e *This) Accept(visitor ExprVisitor) (interface{}, error) {
+	val, err := visitor.VisitThisExpr(e)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
 type Unary struct {
 	Operator token.Token
 	Right    Expr
@@ -68,7 +244,8 @@ func (e *Unary) Accept(visitor ExprVisitor) (interface{}, error) {
 }
 
 type Variable struct {
-	Name token.Token
+	Name    token.Token
+	Binding Binding
 }
 
 func (e *Variable) Accept(visitor ExprVisitor) (interface{}, error) {
@@ -0,0 +1,8 @@
+package interpreter
+
+// LoxCallable is implemented by every value that can appear on the left of a
+// call expression.
+type LoxCallable interface {
+	Arity() int
+	Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error)
+}
@@ -0,0 +1,50 @@
+package interpreter
+
+type LoxClass struct {
+	name       string
+	superclass *LoxClass
+	methods    map[string]*LoxFunction
+}
+
+// NewLoxClass creates a class; superclass is nil when the class doesn't inherit.
+func NewLoxClass(name string, superclass *LoxClass, methods map[string]*LoxFunction) *LoxClass {
+	return &LoxClass{
+		name:       name,
+		superclass: superclass,
+		methods:    methods,
+	}
+}
+
+// findMethod looks up name on the class, then walks up the inheritance chain.
+func (c *LoxClass) findMethod(name string) (*LoxFunction, bool) {
+	if method, ok := c.methods[name]; ok {
+		return method, true
+	}
+	if c.superclass != nil {
+		return c.superclass.findMethod(name)
+	}
+	return nil, false
+}
+
+// Arity is the arity of the class's initializer, or zero without one.
+func (c *LoxClass) Arity() int {
+	if initializer, ok := c.findMethod("init"); ok {
+		return initializer.Arity()
+	}
+	return 0
+}
+
+// Call creates a new instance and runs the initializer on it, if there is one.
+func (c *LoxClass) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	instance := NewLoxInstance(c)
+	if initializer, ok := c.findMethod("init"); ok {
+		if _, err := initializer.Bind(instance).Call(interpreter, arguments); err != nil {
+			return nil, err
+		}
+	}
+	return instance, nil
+}
+
+func (c *LoxClass) String() string {
+	return c.name
+}
@@ -0,0 +1,64 @@
+package interpreter
+
+import (
+	"github.com/joshbochu/golox/loxerror"
+	"github.com/joshbochu/golox/token"
+)
+
+type Environment struct {
+	enclosing *Environment
+	values    map[string]interface{}
+}
+
+// NewEnvironment creates a scope nested inside enclosing, which is nil for the global scope.
+func NewEnvironment(enclosing *Environment) *Environment {
+	return &Environment{
+		enclosing: enclosing,
+		values:    make(map[string]interface{}),
+	}
+}
+
+// Define binds a name to a value, silently replacing any existing binding.
+func (e *Environment) Define(name string, value interface{}) {
+	e.values[name] = value
+}
+
+func (e *Environment) Get(name token.Token) (interface{}, error) {
+	if value, ok := e.values[name.Lexeme]; ok {
+		return value, nil
+	}
+	if e.enclosing != nil {
+		return e.enclosing.Get(name)
+	}
+	return nil, loxerror.NewRuntimeError(name, "Undefined variable '"+name.Lexeme+"'.")
+}
+
+// Assign updates an existing binding; unlike Define it can't create new ones.
+func (e *Environment) Assign(name token.Token, value interface{}) error {
+	if _, ok := e.values[name.Lexeme]; ok {
+		e.values[name.Lexeme] = value
+		return nil
+	}
+	if e.enclosing != nil {
+		return e.enclosing.Assign(name, value)
+	}
+	return loxerror.NewRuntimeError(name, "Undefined variable '"+name.Lexeme+"'.")
+}
+
+// GetAt reads name from the scope distance hops up the chain, as computed by the resolver.
+func (e *Environment) GetAt(distance int, name string) interface{} {
+	return e.ancestor(distance).values[name]
+}
+
+// AssignAt writes name in the scope distance hops up the chain, as computed by the resolver.
+func (e *Environment) AssignAt(distance int, name token.Token, value interface{}) {
+	e.ancestor(distance).values[name.Lexeme] = value
+}
+
+func (e *Environment) ancestor(distance int) *Environment {
+	environment := e
+	for i := 0; i < distance; i++ {
+		environment = environment.enclosing
+	}
+	return environment
+}
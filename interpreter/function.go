@@ -0,0 +1,67 @@
+package interpreter
+
+import (
+	"github.com/joshbochu/golox/stmt"
+)
+
+// maxCallDepth bounds how deeply Lox functions can nest, so runaway recursion
+// is reported rather than overflowing the Go stack. It matches the VM, whose
+// limit on call frames includes the one for the script itself.
+const maxCallDepth = 1<<16 - 1
+
+type LoxFunction struct {
+	declaration   *stmt.Function
+	closure       *Environment
+	isInitializer bool
+}
+
+// NewLoxFunction creates a function that closes over the environment it was declared in.
+func NewLoxFunction(declaration *stmt.Function, closure *Environment, isInitializer bool) *LoxFunction {
+	return &LoxFunction{
+		declaration:   declaration,
+		closure:       closure,
+		isInitializer: isInitializer,
+	}
+}
+
+// Bind returns a copy of the method whose closure defines "this" as instance.
+func (f *LoxFunction) Bind(instance *LoxInstance) *LoxFunction {
+	environment := NewEnvironment(f.closure)
+	environment.Define("this", instance)
+	return NewLoxFunction(f.declaration, environment, f.isInitializer)
+}
+
+func (f *LoxFunction) Arity() int {
+	return len(f.declaration.Params)
+}
+
+func (f *LoxFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	interpreter.callDepth++
+	defer func() { interpreter.callDepth-- }()
+
+	environment := NewEnvironment(f.closure)
+	for i, param := range f.declaration.Params {
+		environment.Define(param.Lexeme, arguments[i])
+	}
+
+	err := interpreter.executeBlock(f.declaration.Body, environment)
+	if ret, ok := err.(*returnValue); ok {
+		if f.isInitializer {
+			return f.closure.GetAt(0, "this"), nil
+		}
+		return ret.value, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// init() always hands back the instance, even when invoked directly
+	if f.isInitializer {
+		return f.closure.GetAt(0, "this"), nil
+	}
+	return nil, nil
+}
+
+func (f *LoxFunction) String() string {
+	return "<fn " + f.declaration.Name.Lexeme + ">"
+}
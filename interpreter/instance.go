@@ -0,0 +1,45 @@
+package interpreter
+
+import (
+	"github.com/joshbochu/golox/loxerror"
+	"github.com/joshbochu/golox/token"
+)
+
+type LoxInstance struct {
+	class  *LoxClass
+	fields map[string]interface{}
+}
+
+func NewLoxInstance(class *LoxClass) *LoxInstance {
+	return &LoxInstance{
+		class:  class,
+		fields: make(map[string]interface{}),
+	}
+}
+
+// Get looks up a field first so fields shadow methods, then falls back to a
+// method bound to this instance.
+func (i *LoxInstance) Get(name token.Token) (interface{}, error) {
+	if value, ok := i.fields[name.Lexeme]; ok {
+		return value, nil
+	}
+	if method, ok := i.class.findMethod(name.Lexeme); ok {
+		return method.Bind(i), nil
+	}
+	return nil, loxerror.NewRuntimeError(name, "Undefined property '"+name.Lexeme+"'.")
+}
+
+// Field returns the value of the field called name, for Go code handed an
+// instance. Methods aren't included.
+func (i *LoxInstance) Field(name string) (interface{}, bool) {
+	value, ok := i.fields[name]
+	return value, ok
+}
+
+func (i *LoxInstance) Set(name token.Token, value interface{}) {
+	i.fields[name.Lexeme] = value
+}
+
+func (i *LoxInstance) String() string {
+	return i.class.name + " instance"
+}
@@ -2,6 +2,10 @@ package interpreter
 
 import (
 	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"time"
 
 	"github.com/joshbochu/golox/expr"
 	"github.com/joshbochu/golox/loxerror"
@@ -9,33 +13,103 @@ import (
 	"github.com/joshbochu/golox/token"
 )
 
-type Interpreter struct{}
+type Interpreter struct {
+	globals     *Environment
+	environment *Environment
+	out         io.Writer
+	random      *rand.Rand
+	reporter    loxerror.Reporter
+	// callDepth counts the Lox functions currently executing.
+	callDepth int
+}
+
+func NewInterpreter(reporter loxerror.Reporter) *Interpreter {
+	globals := NewEnvironment(nil)
+	interpreter := &Interpreter{
+		globals:     globals,
+		environment: globals,
+		out:         os.Stdout,
+		random:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		reporter:    reporter,
+	}
+	interpreter.definePrelude()
+	return interpreter
+}
 
-// VisitVariableExpr implements expr.ExprVisitor.
-func (*Interpreter) VisitVariableExpr(expr *expr.Variable) (interface{}, error) {
-	panic("unimplemented")
+// Resolve implements resolver.Locals by recording the depth on the node
+// itself, so nothing outlives the script that was resolved.
+func (i *Interpreter) Resolve(e expr.Expr, depth int) {
+	binding := expr.Binding{Local: true, Depth: depth}
+	switch e := e.(type) {
+	case *expr.Assign:
+		e.Binding = binding
+	case *expr.Super:
+		e.Binding = binding
+	case *expr.This:
+		e.Binding = binding
+	case *expr.Variable:
+		e.Binding = binding
+	}
 }
 
-// VisitVarStmt implements stmt.StmtVisitor.
-func (*Interpreter) VisitVarStmt(expr *stmt.Var) (interface{}, error) {
-	panic("unimplemented")
+func (i *Interpreter) lookUpVariable(name token.Token, binding expr.Binding) (interface{}, error) {
+	if binding.Local {
+		return i.environment.GetAt(binding.Depth, name.Lexeme), nil
+	}
+	return i.globals.Get(name)
 }
 
-func NewInterpreter() *Interpreter {
-	return &Interpreter{}
+// SetOutput redirects what print writes, which goes to stdout by default.
+func (i *Interpreter) SetOutput(w io.Writer) {
+	i.out = w
 }
 
+// Interpret executes statements in order, stopping at and reporting the
+// first runtime error.
 func (i *Interpreter) Interpret(statements []stmt.Stmt) {
-	for _, statement := range statements {
-		i.execute(statement)
+	if _, err := i.Execute(statements); err != nil {
+		if runtimeErr, ok := err.(*loxerror.RuntimeError); ok {
+			i.reporter.Report(runtimeErr.Diagnostic())
+		}
 	}
+}
 
+// Execute runs statements like Interpret but returns the first runtime error
+// instead of reporting it. When the last statement is an expression
+// statement, its value is returned.
+func (i *Interpreter) Execute(statements []stmt.Stmt) (interface{}, error) {
+	for n, statement := range statements {
+		if last, ok := statement.(*stmt.Expression); ok && n == len(statements)-1 {
+			return i.evaluate(last.Expression)
+		}
+		if _, err := i.execute(statement); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
 }
 
 func (i *Interpreter) execute(stmt stmt.Stmt) (interface{}, error) {
 	return stmt.Accept(i)
 }
 
+// executeBlock runs statements in environment, restoring the previous
+// environment afterwards even if a statement fails.
+func (i *Interpreter) executeBlock(statements []stmt.Stmt, environment *Environment) error {
+	previous := i.environment
+	defer func() {
+		i.environment = previous
+	}()
+
+	i.environment = environment
+	for _, statement := range statements {
+		if _, err := i.execute(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // TODO
 func stringify(object interface{}) string {
 	if object == nil {
@@ -57,21 +131,337 @@ func (i *Interpreter) evaluate(expr expr.Expr) (interface{}, error) {
 	return expr.Accept(i)
 }
 
+func (i *Interpreter) VisitBlockStmt(stmt *stmt.Block) (interface{}, error) {
+	return nil, i.executeBlock(stmt.Statements, NewEnvironment(i.environment))
+}
+
+func (i *Interpreter) VisitClassStmt(stmt *stmt.Class) (interface{}, error) {
+	var superclass *LoxClass
+	if stmt.Superclass != nil {
+		value, err := i.evaluate(stmt.Superclass)
+		if err != nil {
+			return nil, err
+		}
+		class, ok := value.(*LoxClass)
+		if !ok {
+			return nil, loxerror.NewRuntimeError(stmt.Superclass.Name, "Superclass must be a class.")
+		}
+		superclass = class
+	}
+
+	i.environment.Define(stmt.Name.Lexeme, nil)
+
+	// methods of a subclass close over an extra scope binding "super"
+	enclosing := i.environment
+	if superclass != nil {
+		i.environment = NewEnvironment(i.environment)
+		i.environment.Define("super", superclass)
+	}
+
+	methods := make(map[string]*LoxFunction)
+	for _, method := range stmt.Methods {
+		methods[method.Name.Lexeme] = NewLoxFunction(method, i.environment, method.Name.Lexeme == "init")
+	}
+
+	i.environment = enclosing
+
+	class := NewLoxClass(stmt.Name.Lexeme, superclass, methods)
+	if err := i.environment.Assign(stmt.Name, class); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
 func (i *Interpreter) VisitExpressionStmt(stmt *stmt.Expression) (interface{}, error) {
-	i.evaluate(stmt.Expression)
+	if _, err := i.evaluate(stmt.Expression); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// VisitForInStmt runs the body in a fresh scope per value, so closures
+// created in the body each capture their own loop variable.
+func (i *Interpreter) VisitForInStmt(forIn *stmt.ForIn) (interface{}, error) {
+	iterable, err := i.evaluate(forIn.Iterable)
+	if err != nil {
+		return nil, err
+	}
+	next, err := i.iterate(forIn.In, iterable)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		value, ok, err := next()
+		if err != nil || !ok {
+			return nil, err
+		}
+		environment := NewEnvironment(i.environment)
+		environment.Define(forIn.Name.Lexeme, value)
+		if err := i.executeBlock([]stmt.Stmt{forIn.Body}, environment); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (i *Interpreter) VisitFunctionStmt(stmt *stmt.Function) (interface{}, error) {
+	function := NewLoxFunction(stmt, i.environment, false)
+	i.environment.Define(stmt.Name.Lexeme, function)
+	return nil, nil
+}
+
+func (i *Interpreter) VisitIfStmt(stmt *stmt.If) (interface{}, error) {
+	condition, err := i.evaluate(stmt.Condition)
+	if err != nil {
+		return nil, err
+	}
+	if isTruthy(condition) {
+		return i.execute(stmt.ThenBranch)
+	} else if stmt.ElseBranch != nil {
+		return i.execute(stmt.ElseBranch)
+	}
 	return nil, nil
 }
 
 func (i *Interpreter) VisitPrintStmt(stmt *stmt.Print) (interface{}, error) {
-	v, _ := i.evaluate(stmt.Expression)
-	fmt.Println(stringify(v))
+	v, err := i.evaluate(stmt.Expression)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(i.out, stringify(v))
 	return nil, nil
 }
 
+func (i *Interpreter) VisitReturnStmt(stmt *stmt.Return) (interface{}, error) {
+	var value interface{}
+	if stmt.Value != nil {
+		v, err := i.evaluate(stmt.Value)
+		if err != nil {
+			return nil, err
+		}
+		value = v
+	}
+	return nil, &returnValue{value: value}
+}
+
+func (i *Interpreter) VisitVarStmt(stmt *stmt.Var) (interface{}, error) {
+	var value interface{}
+	if stmt.Initializer != nil {
+		v, err := i.evaluate(stmt.Initializer)
+		if err != nil {
+			return nil, err
+		}
+		value = v
+	}
+	i.environment.Define(stmt.Name.Lexeme, value)
+	return nil, nil
+}
+
+func (i *Interpreter) VisitWhileStmt(stmt *stmt.While) (interface{}, error) {
+	for {
+		condition, err := i.evaluate(stmt.Condition)
+		if err != nil {
+			return nil, err
+		}
+		if !isTruthy(condition) {
+			return nil, nil
+		}
+		if _, err := i.execute(stmt.Body); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (i *Interpreter) VisitVariableExpr(expr *expr.Variable) (interface{}, error) {
+	return i.lookUpVariable(expr.Name, expr.Binding)
+}
+
+func (i *Interpreter) VisitAssignExpr(expr *expr.Assign) (interface{}, error) {
+	value, err := i.evaluate(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+	if expr.Binding.Local {
+		i.environment.AssignAt(expr.Binding.Depth, expr.Name, value)
+	} else if err := i.globals.Assign(expr.Name, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
 func (i *Interpreter) VisitLiteralExpr(expr *expr.Literal) (interface{}, error) {
 	return expr.Value, nil
 }
 
+// VisitLogicalExpr short-circuits and returns the operand that decided the
+// result rather than coercing it to a bool.
+func (i *Interpreter) VisitLogicalExpr(expr *expr.Logical) (interface{}, error) {
+	left, err := i.evaluate(expr.Left)
+	if err != nil {
+		return nil, err
+	}
+
+	if expr.Operator.Type == token.OR {
+		if isTruthy(left) {
+			return left, nil
+		}
+	} else {
+		if !isTruthy(left) {
+			return left, nil
+		}
+	}
+	return i.evaluate(expr.Right)
+}
+
+func (i *Interpreter) VisitGetExpr(expr *expr.Get) (interface{}, error) {
+	object, err := i.evaluate(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+	switch object := object.(type) {
+	case *LoxInstance:
+		return object.Get(expr.Name)
+	case *LoxList:
+		return object.Get(expr.Name)
+	case *LoxMap:
+		return object.Get(expr.Name)
+	}
+	return nil, loxerror.NewRuntimeError(expr.Name, "Only instances have properties.")
+}
+
+func (i *Interpreter) VisitIndexExpr(expr *expr.Index) (interface{}, error) {
+	object, err := i.evaluate(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+	index, err := i.evaluate(expr.Index)
+	if err != nil {
+		return nil, err
+	}
+	switch object := object.(type) {
+	case *LoxList:
+		n, err := object.index(expr.Bracket, index)
+		if err != nil {
+			return nil, err
+		}
+		return object.elements[n], nil
+	case *LoxMap:
+		if err := checkKey(index); err != nil {
+			return nil, loxerror.NewRuntimeError(expr.Bracket, err.Error())
+		}
+		value, ok := object.get(index)
+		if !ok {
+			return nil, loxerror.NewRuntimeError(expr.Bracket, "Undefined key '"+stringify(index)+"'.")
+		}
+		return value, nil
+	}
+	return nil, loxerror.NewRuntimeError(expr.Bracket, "Only lists and maps can be indexed.")
+}
+
+func (i *Interpreter) VisitListExpr(expr *expr.List) (interface{}, error) {
+	elements := make([]interface{}, 0, len(expr.Elements))
+	for _, element := range expr.Elements {
+		value, err := i.evaluate(element)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, value)
+	}
+	return NewLoxList(elements), nil
+}
+
+func (i *Interpreter) VisitMapExpr(expr *expr.Map) (interface{}, error) {
+	entries := NewLoxMap()
+	for n, keyExpr := range expr.Keys {
+		key, err := i.evaluate(keyExpr)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkKey(key); err != nil {
+			return nil, loxerror.NewRuntimeError(expr.Brace, err.Error())
+		}
+		value, err := i.evaluate(expr.Values[n])
+		if err != nil {
+			return nil, err
+		}
+		entries.set(key, value)
+	}
+	return entries, nil
+}
+
+func (i *Interpreter) VisitSetExpr(expr *expr.Set) (interface{}, error) {
+	object, err := i.evaluate(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+	instance, ok := object.(*LoxInstance)
+	if !ok {
+		return nil, loxerror.NewRuntimeError(expr.Name, "Only instances have fields.")
+	}
+
+	value, err := i.evaluate(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+	instance.Set(expr.Name, value)
+	return value, nil
+}
+
+func (i *Interpreter) VisitSetIndexExpr(expr *expr.SetIndex) (interface{}, error) {
+	object, err := i.evaluate(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+	index, err := i.evaluate(expr.Index)
+	if err != nil {
+		return nil, err
+	}
+	value, err := i.evaluate(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+	switch object := object.(type) {
+	case *LoxList:
+		n, err := object.index(expr.Bracket, index)
+		if err != nil {
+			return nil, err
+		}
+		object.elements[n] = value
+		return value, nil
+	case *LoxMap:
+		if err := checkKey(index); err != nil {
+			return nil, loxerror.NewRuntimeError(expr.Bracket, err.Error())
+		}
+		object.set(index, value)
+		return value, nil
+	}
+	return nil, loxerror.NewRuntimeError(expr.Bracket, "Only lists and maps can be indexed.")
+}
+
+func (i *Interpreter) VisitStringifyExpr(expr *expr.Stringify) (interface{}, error) {
+	value, err := i.evaluate(expr.Expression)
+	if err != nil {
+		return nil, err
+	}
+	return stringify(value), nil
+}
+
+func (i *Interpreter) VisitSuperExpr(expr *expr.Super) (interface{}, error) {
+	distance := expr.Binding.Depth
+	superclass := i.environment.GetAt(distance, "super").(*LoxClass)
+	// "this" is always bound in the scope just inside the one holding "super"
+	object := i.environment.GetAt(distance-1, "this").(*LoxInstance)
+
+	method, ok := superclass.findMethod(expr.Method.Lexeme)
+	if !ok {
+		return nil, loxerror.NewRuntimeError(expr.Method, "Undefined property '"+expr.Method.Lexeme+"'.")
+	}
+	return method.Bind(object), nil
+}
+
+func (i *Interpreter) VisitThisExpr(expr *expr.This) (interface{}, error) {
+	return i.lookUpVariable(expr.Keyword, expr.Binding)
+}
+
 func (i *Interpreter) VisitGroupingExpr(expr *expr.Grouping) (interface{}, error) {
 	return i.evaluate(expr.Expression)
 }
@@ -152,8 +542,43 @@ func (i *Interpreter) VisitBinaryExpr(expr *expr.Binary) (interface{}, error) {
 	return nil, nil
 }
 
+func (i *Interpreter) VisitCallExpr(expr *expr.Call) (interface{}, error) {
+	callee, err := i.evaluate(expr.Callee)
+	if err != nil {
+		return nil, err
+	}
+
+	arguments := make([]interface{}, 0, len(expr.Arguments))
+	for _, argument := range expr.Arguments {
+		v, err := i.evaluate(argument)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, v)
+	}
+
+	function, ok := callee.(LoxCallable)
+	if !ok {
+		return nil, loxerror.NewRuntimeError(expr.Paren, "Can only call functions and classes.")
+	}
+	if len(arguments) != function.Arity() {
+		return nil, loxerror.NewRuntimeError(expr.Paren, fmt.Sprintf("Expected %d arguments but got %d.", function.Arity(), len(arguments)))
+	}
+	if i.callDepth == maxCallDepth {
+		return nil, loxerror.NewRuntimeError(expr.Paren, "Stack overflow.")
+	}
+	result, err := function.Call(i, arguments)
+	if nativeErr, ok := err.(*nativeError); ok {
+		return nil, loxerror.NewRuntimeError(expr.Paren, nativeErr.Error())
+	}
+	return result, err
+}
+
 func (i *Interpreter) VisitUnaryExpr(expr *expr.Unary) (interface{}, error) {
-	rightObj, _ := i.evaluate(expr.Right)
+	rightObj, err := i.evaluate(expr.Right)
+	if err != nil {
+		return nil, err
+	}
 	switch expr.Operator.Type {
 	case token.BANG:
 		return !isTruthy(rightObj), nil
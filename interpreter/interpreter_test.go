@@ -1,14 +1,22 @@
 package interpreter
 
 import (
+	"io"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/joshbochu/golox/expr"
+	"github.com/joshbochu/golox/loxerror"
+	"github.com/joshbochu/golox/parser"
+	"github.com/joshbochu/golox/resolver"
+	"github.com/joshbochu/golox/scanner"
+	"github.com/joshbochu/golox/stmt"
 	"github.com/joshbochu/golox/token"
 )
 
 func TestInterpreter(t *testing.T) {
-	interpreter := NewInterpreter()
+	interpreter := NewInterpreter(&loxerror.Collector{})
 	tests := []struct {
 		name       string
 		expression expr.Expr
@@ -121,6 +129,26 @@ func TestInterpreter(t *testing.T) {
 			expectErr:  true,
 			errMsg:     "operands must be numbers",
 		},
+		{
+			name:       `"hi" or 2 -> "hi"`,
+			expression: &expr.Logical{Left: &expr.Literal{Value: "hi"}, Operator: token.NewToken(token.OR, "or", nil, 1), Right: &expr.Literal{Value: float64(2)}},
+			expected:   "hi",
+		},
+		{
+			name:       "nil or 2 -> 2",
+			expression: &expr.Logical{Left: &expr.Literal{Value: nil}, Operator: token.NewToken(token.OR, "or", nil, 1), Right: &expr.Literal{Value: float64(2)}},
+			expected:   float64(2),
+		},
+		{
+			name:       "nil and 2 -> nil",
+			expression: &expr.Logical{Left: &expr.Literal{Value: nil}, Operator: token.NewToken(token.AND, "and", nil, 1), Right: &expr.Literal{Value: float64(2)}},
+			expected:   nil,
+		},
+		{
+			name:       "false and -\"x\" short-circuits",
+			expression: &expr.Logical{Left: &expr.Literal{Value: false}, Operator: token.NewToken(token.AND, "and", nil, 1), Right: &expr.Unary{Operator: token.NewToken(token.MINUS, "-", nil, 1), Right: &expr.Literal{Value: "x"}}},
+			expected:   false,
+		},
 		{
 			name:       "Comparison of number and string",
 			expression: &expr.Binary{Left: &expr.Literal{Value: float64(5)}, Operator: token.NewToken(token.GREATER, ">", nil, 1), Right: &expr.Literal{Value: "hello"}},
@@ -158,3 +186,760 @@ func TestInterpreter(t *testing.T) {
 		})
 	}
 }
+
+func TestEnvironment(t *testing.T) {
+	interpreter := NewInterpreter(&loxerror.Collector{})
+	name := token.NewToken(token.IDENTIFIER, "x", nil, 1)
+
+	_, err := interpreter.evaluate(&expr.Variable{Name: name})
+	if err == nil || err.Error() != "Undefined variable 'x'." {
+		t.Fatalf("Expected undefined variable error, got %v", err)
+	}
+	if runtimeErr, ok := err.(*loxerror.RuntimeError); !ok || runtimeErr.Token != name {
+		t.Errorf("Expected runtime error at token %v, got %v", name, err)
+	}
+
+	_, err = interpreter.execute(&stmt.Var{Name: name, Initializer: &expr.Literal{Value: float64(1)}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	val, err := interpreter.evaluate(&expr.Variable{Name: name})
+	if err != nil || val != float64(1) {
+		t.Errorf("Expected 1, got %v (err %v)", val, err)
+	}
+
+	_, err = interpreter.execute(&stmt.Var{Name: name})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	val, err = interpreter.evaluate(&expr.Variable{Name: name})
+	if err != nil || val != nil {
+		t.Errorf("Expected redeclaration to reset x to nil, got %v (err %v)", val, err)
+	}
+}
+
+func TestAssign(t *testing.T) {
+	interpreter := NewInterpreter(&loxerror.Collector{})
+	name := token.NewToken(token.IDENTIFIER, "x", nil, 1)
+
+	_, err := interpreter.evaluate(&expr.Assign{Name: name, Value: &expr.Literal{Value: float64(2)}})
+	if err == nil || err.Error() != "Undefined variable 'x'." {
+		t.Fatalf("Expected assignment to undeclared variable to fail, got %v", err)
+	}
+
+	interpreter.execute(&stmt.Var{Name: name, Initializer: &expr.Literal{Value: float64(1)}})
+	val, err := interpreter.evaluate(&expr.Assign{Name: name, Value: &expr.Literal{Value: float64(2)}})
+	if err != nil || val != float64(2) {
+		t.Errorf("Expected assignment to evaluate to 2, got %v (err %v)", val, err)
+	}
+	val, _ = interpreter.evaluate(&expr.Variable{Name: name})
+	if val != float64(2) {
+		t.Errorf("Expected x to be 2 after assignment, got %v", val)
+	}
+}
+
+func TestBlockScope(t *testing.T) {
+	interpreter := NewInterpreter(&loxerror.Collector{})
+	x := token.NewToken(token.IDENTIFIER, "x", nil, 1)
+	y := token.NewToken(token.IDENTIFIER, "y", nil, 1)
+
+	interpreter.execute(&stmt.Var{Name: x, Initializer: &expr.Literal{Value: "outer"}})
+
+	// { var x = "inner"; var y = x; }
+	_, err := interpreter.execute(&stmt.Block{Statements: []stmt.Stmt{
+		&stmt.Var{Name: x, Initializer: &expr.Literal{Value: "inner"}},
+		&stmt.Var{Name: y, Initializer: &expr.Variable{Name: x}},
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val, _ := interpreter.evaluate(&expr.Variable{Name: x}); val != "outer" {
+		t.Errorf("Expected inner declaration to be discarded, got %v", val)
+	}
+	if _, err := interpreter.evaluate(&expr.Variable{Name: y}); err == nil {
+		t.Errorf("Expected y to go out of scope after the block")
+	}
+
+	// { x = "assigned"; var y = -"oops"; }
+	_, err = interpreter.execute(&stmt.Block{Statements: []stmt.Stmt{
+		&stmt.Expression{Expression: &expr.Assign{Name: x, Value: &expr.Literal{Value: "assigned"}}},
+		&stmt.Var{Name: y, Initializer: &expr.Unary{Operator: token.NewToken(token.MINUS, "-", nil, 1), Right: &expr.Literal{Value: "oops"}}},
+	}})
+	if err == nil {
+		t.Fatalf("Expected runtime error from block")
+	}
+	if val, _ := interpreter.evaluate(&expr.Variable{Name: x}); val != "assigned" {
+		t.Errorf("Expected assignment in block to update the outer x, got %v", val)
+	}
+	if interpreter.environment.enclosing != nil {
+		t.Errorf("Expected environment to be restored after a runtime error")
+	}
+}
+
+func TestWhile(t *testing.T) {
+	interpreter := NewInterpreter(&loxerror.Collector{})
+	i := token.NewToken(token.IDENTIFIER, "i", nil, 1)
+
+	// var i = 0; while (i < 3) i = i + 1;
+	interpreter.execute(&stmt.Var{Name: i, Initializer: &expr.Literal{Value: float64(0)}})
+	_, err := interpreter.execute(&stmt.While{
+		Condition: &expr.Binary{Left: &expr.Variable{Name: i}, Operator: token.NewToken(token.LESS, "<", nil, 1), Right: &expr.Literal{Value: float64(3)}},
+		Body: &stmt.Expression{Expression: &expr.Assign{
+			Name:  i,
+			Value: &expr.Binary{Left: &expr.Variable{Name: i}, Operator: token.NewToken(token.PLUS, "+", nil, 1), Right: &expr.Literal{Value: float64(1)}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val, _ := interpreter.evaluate(&expr.Variable{Name: i}); val != float64(3) {
+		t.Errorf("Expected loop to stop at 3, got %v", val)
+	}
+}
+
+// run parses and resolves source and executes it statement by statement,
+// stopping at the first runtime error.
+func run(t *testing.T, interpreter *Interpreter, source string) error {
+	t.Helper()
+	reporter := &loxerror.Collector{}
+	statements, err := parser.NewParser(scanner.NewScanner(source, reporter).ScanTokens(), reporter).Parse()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %v", err)
+	}
+	if err := resolver.NewResolver(interpreter, reporter).Resolve(statements); err != nil {
+		t.Fatalf("Unexpected resolve error: %v", err)
+	}
+	for _, statement := range statements {
+		if _, err := interpreter.execute(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// global looks up a variable in the interpreter's global scope.
+func global(interpreter *Interpreter, name string) interface{} {
+	val, _ := interpreter.globals.Get(token.NewToken(token.IDENTIFIER, name, nil, 1))
+	return val
+}
+
+func TestFunctions(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected interface{}
+		errMsg   string
+	}{
+		{
+			name:     "Return value",
+			source:   "fun add(a, b) { return a + b; } var result = add(1, 2);",
+			expected: float64(3),
+		},
+		{
+			name:     "Implicit nil return",
+			source:   "fun f() { 1; } var result = f();",
+			expected: nil,
+		},
+		{
+			name:     "Return unwinds loops",
+			source:   "fun f() { while (true) { return \"done\"; } } var result = f();",
+			expected: "done",
+		},
+		{
+			name:     "Recursion",
+			source:   "fun fib(n) { if (n < 2) return n; return fib(n - 2) + fib(n - 1); } var result = fib(10);",
+			expected: float64(55),
+		},
+		{
+			name: "Closure counter",
+			source: `
+				fun makeCounter() {
+					var i = 0;
+					fun count() { i = i + 1; return i; }
+					return count;
+				}
+				var counter = makeCounter();
+				counter();
+				var result = counter();`,
+			expected: float64(2),
+		},
+		{
+			name:     "Callbacks",
+			source:   "fun twice(f, x) { return f(f(x)); } fun inc(n) { return n + 1; } var result = twice(inc, 1);",
+			expected: float64(3),
+		},
+		{
+			name: "Closure keeps binding after global is redeclared",
+			source: `
+				var a = "global";
+				var result;
+				{
+					fun get() { return a; }
+					result = get();
+					var a = "block";
+					result = result + get();
+				}`,
+			expected: "globalglobal",
+		},
+		{
+			name:     "String interpolation stringifies values",
+			source:   "fun f() { return 1.5; } class C {} var result = \"${f()} ${nil} ${true} ${C()} ${\"s\"}\";",
+			expected: "1.5 nil true C instance s",
+		},
+		{
+			name:   "Wrong argument count",
+			source: "fun add(a, b) { return a + b; } add(1, 2, 3);",
+			errMsg: "Expected 2 arguments but got 3.",
+		},
+		{
+			name:   "Calling a non-callable",
+			source: "\"not a function\"();",
+			errMsg: "Can only call functions and classes.",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			interpreter := NewInterpreter(&loxerror.Collector{})
+			err := run(t, interpreter, test.source)
+			if test.errMsg != "" {
+				if err == nil || err.Error() != test.errMsg {
+					t.Errorf("Expected error %q, got %v", test.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if val := global(interpreter, "result"); val != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, val)
+			}
+		})
+	}
+}
+
+func TestClasses(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected interface{}
+		errMsg   string
+		errLine  int
+	}{
+		{
+			name:     "Fields",
+			source:   "class Box {} var box = Box(); box.value = 1; var result = box.value;",
+			expected: float64(1),
+		},
+		{
+			name:     "Methods bind this",
+			source:   "class Greeter { greet() { return \"hi \" + this.name; } } var g = Greeter(); g.name = \"bob\"; var result = g.greet();",
+			expected: "hi bob",
+		},
+		{
+			name:     "Bound methods remember their instance",
+			source:   "class C { get() { return this.v; } } var a = C(); a.v = 1; var b = C(); b.v = 2; b.get = a.get; var result = b.get();",
+			expected: float64(1),
+		},
+		{
+			name:     "Initializer",
+			source:   "class P { init(x, y) { this.x = x; this.y = y; } } var p = P(1, 2); var result = p.x + p.y;",
+			expected: float64(3),
+		},
+		{
+			name:     "Initializer returns this",
+			source:   "class P { init() { this.n = 1; return; } } var p = P(); var result = p.init() == p;",
+			expected: true,
+		},
+		{
+			name:     "Inherited methods",
+			source:   "class A { m() { return \"A\"; } } class B < A {} var result = B().m();",
+			expected: "A",
+		},
+		{
+			name:     "Overridden methods",
+			source:   "class A { m() { return \"A\"; } } class B < A { m() { return \"B\"; } } var result = B().m();",
+			expected: "B",
+		},
+		{
+			name: "Super skips the instance's own class",
+			source: `
+				class A { m() { return "A"; } }
+				class B < A { m() { return "B"; } test() { return super.m(); } }
+				class C < B {}
+				var result = C().test();`,
+			expected: "A",
+		},
+		{
+			name:     "Super binds this",
+			source:   "class A { name() { return this.n; } } class B < A { init() { this.n = \"b\"; } name() { return \"?\"; } get() { return super.name(); } } var result = B().get();",
+			expected: "b",
+		},
+		{
+			name:     "Inherited initializer",
+			source:   "class A { init(n) { this.n = n; } } class B < A {} var result = B(1).n;",
+			expected: float64(1),
+		},
+		{
+			name:    "Superclass must be a class",
+			source:  "var NotAClass = \"nope\";\nclass B < NotAClass {}",
+			errMsg:  "Superclass must be a class.",
+			errLine: 2,
+		},
+		{
+			name:    "Undefined super method",
+			source:  "class A {}\nclass B < A { m() { return super.missing(); } }\nB().m();",
+			errMsg:  "Undefined property 'missing'.",
+			errLine: 2,
+		},
+		{
+			name:   "Initializer arity",
+			source: "class P { init(x) {} } P();",
+			errMsg: "Expected 1 arguments but got 0.",
+		},
+		{
+			name:    "Property on non-instance",
+			source:  "var s = \"str\";\ns.length;",
+			errMsg:  "Only instances have properties.",
+			errLine: 2,
+		},
+		{
+			name:    "Undefined property",
+			source:  "class C {}\nC().x;",
+			errMsg:  "Undefined property 'x'.",
+			errLine: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			interpreter := NewInterpreter(&loxerror.Collector{})
+			err := run(t, interpreter, test.source)
+			if test.errMsg != "" {
+				if err == nil || err.Error() != test.errMsg {
+					t.Fatalf("Expected error %q, got %v", test.errMsg, err)
+				}
+				if test.errLine != 0 && err.(*loxerror.RuntimeError).Token.Line != test.errLine {
+					t.Errorf("Expected error on line %d, got %d", test.errLine, err.(*loxerror.RuntimeError).Token.Line)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if val := global(interpreter, "result"); val != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, val)
+			}
+		})
+	}
+}
+
+func TestLists(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+		errMsg   string
+		errCol   int
+	}{
+		{
+			name:     "Literal",
+			source:   "var result = [1, \"two\", nil, [true]];",
+			expected: "[1, two, nil, [true]]",
+		},
+		{
+			name:     "Index",
+			source:   "var xs = [1, 2, 3]; var result = xs[1];",
+			expected: "2",
+		},
+		{
+			name:     "Index assignment",
+			source:   "var xs = [1, 2, 3]; xs[2] = xs[0] = 5; var result = xs;",
+			expected: "[5, 2, 5]",
+		},
+		{
+			name:     "Lists are shared",
+			source:   "var xs = []; var ys = xs; ys.push(1); var result = xs;",
+			expected: "[1]",
+		},
+		{
+			name:     "Push and pop",
+			source:   "var xs = [1]; xs.push(2); xs.push(3); var result = \"${xs.pop()} ${xs}\";",
+			expected: "3 [1, 2]",
+		},
+		{
+			name:     "Len",
+			source:   "var result = [1, 2, 3].len() + len([4]);",
+			expected: "4",
+		},
+		{
+			name:     "Slice copies",
+			source:   "var xs = [1, 2, 3, 4]; var ys = xs.slice(1, 3); ys[0] = 9; var result = \"${xs} ${ys}\";",
+			expected: "[1, 2, 3, 4] [9, 3]",
+		},
+		{
+			name:   "Negative index",
+			source: "var xs = [1]; xs[-1];",
+			errMsg: "List index can't be negative.",
+			errCol: 17,
+		},
+		{
+			name:   "Out of bounds",
+			source: "var xs = [1]; xs[1] = 2;",
+			errMsg: "List index 1 is out of bounds for a list of length 1.",
+			errCol: 17,
+		},
+		{
+			name:   "Fractional index",
+			source: "[1][0.5];",
+			errMsg: "List index must be an integer.",
+			errCol: 4,
+		},
+		{
+			name:   "Indexing a non-list",
+			source: "\"abc\"[0];",
+			errMsg: "Only lists and maps can be indexed.",
+			errCol: 6,
+		},
+		{
+			name:   "Pop from empty",
+			source: "[].pop();",
+			errMsg: "Can't pop from an empty list.",
+		},
+		{
+			name:   "Slice out of range",
+			source: "[1, 2].slice(1, 3);",
+			errMsg: "Slice [1, 3) out of range for a list of length 2.",
+		},
+		{
+			name:   "Undefined method",
+			source: "[].size();",
+			errMsg: "Undefined property 'size'.",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			interpreter := NewInterpreter(&loxerror.Collector{})
+			err := run(t, interpreter, test.source)
+			if test.errMsg != "" {
+				if err == nil || err.Error() != test.errMsg {
+					t.Fatalf("Expected error %q, got %v", test.errMsg, err)
+				}
+				if test.errCol != 0 && err.(*loxerror.RuntimeError).Token.Column != test.errCol {
+					t.Errorf("Expected error at column %d, got %d", test.errCol, err.(*loxerror.RuntimeError).Token.Column)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if val := stringify(global(interpreter, "result")); val != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, val)
+			}
+		})
+	}
+}
+
+func TestMaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+		errMsg   string
+		errCol   int
+	}{
+		{
+			name:     "Literal keeps insertion order",
+			source:   "var result = {\"b\": 1, \"a\": 2, 3: nil, true: [4]};",
+			expected: "{b: 1, a: 2, 3: nil, true: [4]}",
+		},
+		{
+			name:     "Later duplicate keys win",
+			source:   "var result = {\"a\": 1, \"b\": 2, \"a\": 3};",
+			expected: "{a: 3, b: 2}",
+		},
+		{
+			name:     "Lookup",
+			source:   "var m = {\"a\": 1, nil: 2}; var result = m[\"a\"] + m[nil];",
+			expected: "3",
+		},
+		{
+			name:     "Assignment adds and replaces",
+			source:   "var m = {1: \"one\"}; m[2] = \"two\"; m[1] = \"uno\"; var result = m;",
+			expected: "{1: uno, 2: two}",
+		},
+		{
+			name:     "Keys and values",
+			source:   "var m = {\"x\": 1, \"y\": 2}; var result = \"${m.keys()} ${m.values()}\";",
+			expected: "[x, y] [1, 2]",
+		},
+		{
+			name:     "Has and remove",
+			source:   "var m = {\"x\": 1, \"y\": 2}; var removed = m.remove(\"x\"); var result = \"${removed} ${m.has(\"x\")} ${m.has(\"y\")} ${m.remove(\"x\")} ${m}\";",
+			expected: "1 false true nil {y: 2}",
+		},
+		{
+			name:     "Removed keys go to the back when re-added",
+			source:   "var m = {\"a\": 1, \"b\": 2}; m.remove(\"a\"); m[\"a\"] = 3; var result = m.keys();",
+			expected: "[b, a]",
+		},
+		{
+			name:     "Len",
+			source:   "var m = {1: 1, 2: 2}; var result = m.len() + len(m) + len({});",
+			expected: "4",
+		},
+		{
+			name:     "Map statement",
+			source:   "var result = 1; {\"a\": result = 2};",
+			expected: "2",
+		},
+		{
+			name:   "Missing key",
+			source: "var m = {}; m[\"a\"];",
+			errMsg: "Undefined key 'a'.",
+			errCol: 14,
+		},
+		{
+			name:   "List key",
+			source: "var m = {}; m[[]] = 1;",
+			errMsg: "Can't use a list as a map key.",
+			errCol: 14,
+		},
+		{
+			name:   "Instance key",
+			source: "class C {} var m = {C(): 1};",
+			errMsg: "Can't use an instance as a map key.",
+			errCol: 20,
+		},
+		{
+			name:   "Map key",
+			source: "var m = {}; m.has({});",
+			errMsg: "Can't use a map as a map key.",
+		},
+		{
+			name:   "NaN key",
+			source: "var m = {}; m[0/0];",
+			errMsg: "Can't use NaN as a map key.",
+		},
+		{
+			name:   "Function key",
+			source: "fun f() {} var m = {f: 1};",
+			errMsg: "Can't use a function as a map key.",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			interpreter := NewInterpreter(&loxerror.Collector{})
+			err := run(t, interpreter, test.source)
+			if test.errMsg != "" {
+				if err == nil || err.Error() != test.errMsg {
+					t.Fatalf("Expected error %q, got %v", test.errMsg, err)
+				}
+				if test.errCol != 0 && err.(*loxerror.RuntimeError).Token.Column != test.errCol {
+					t.Errorf("Expected error at column %d, got %d", test.errCol, err.(*loxerror.RuntimeError).Token.Column)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if val := stringify(global(interpreter, "result")); val != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, val)
+			}
+		})
+	}
+}
+
+func TestForIn(t *testing.T) {
+	iterable := `
+		class Countdown {
+			init(n) { this.n = n; }
+			iter() { return CountdownIter(this.n); }
+		}
+		class CountdownIter {
+			init(n) { this.n = n; }
+			hasNext() { return this.n > 0; }
+			next() {
+				this.n = this.n - 1;
+				return this.n + 1;
+			}
+		}
+		class Each {
+			init(list) { this.list = list; this.n = 0; }
+			iter() { return this; }
+			hasNext() { return this.n < this.list.len(); }
+			next() {
+				this.n = this.n + 1;
+				return this.list[this.n - 1];
+			}
+		}
+	`
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+		errMsg   string
+		errCol   int
+	}{
+		{
+			name:     "List",
+			source:   "var result = \"\"; for (var x in [1, 2, 3]) result = result + toString(x);",
+			expected: "123",
+		},
+		{
+			name:     "Elements pushed during the loop are visited",
+			source:   "var result = [1]; for (var x in result) if (x < 3) result.push(x + 1);",
+			expected: "[1, 2, 3]",
+		},
+		{
+			name:     "Map keys in insertion order",
+			source:   "var m = {\"b\": 1, \"a\": 2}; var result = \"\"; for (var k in m) { m.remove(k); result = result + k; }",
+			expected: "ba",
+		},
+		{
+			name:     "String characters",
+			source:   "var result = []; for (var c in \"añb\") result.push(c);",
+			expected: "[a, ñ, b]",
+		},
+		{
+			name:     "Iterator protocol",
+			source:   iterable + "var result = []; for (var n in Countdown(3)) result.push(n);",
+			expected: "[3, 2, 1]",
+		},
+		{
+			name:     "Iterators can yield nil",
+			source:   iterable + "var result = []; for (var x in Each([1, nil, 2])) result.push(x);",
+			expected: "[1, nil, 2]",
+		},
+		{
+			name:     "Each iteration has its own variable",
+			source:   "var fs = []; for (var x in [1, 2]) { fun f() { return x; } fs.push(f); } var result = fs[0]() + fs[1]() * 10;",
+			expected: "21",
+		},
+		{
+			name:     "Return from inside the loop",
+			source:   "fun first(xs) { for (var x in xs) return x; return nil; } var result = first([7, 8]);",
+			expected: "7",
+		},
+		{
+			name:     "C-style loop still works",
+			source:   "var result = 0; for (var i = 0; i < 3; i = i + 1) result = result + i;",
+			expected: "3",
+		},
+		{
+			name:   "Not iterable",
+			source: "for (var x in 5) print x;",
+			errMsg: "Can only iterate over lists, maps, strings, and instances with an iter() method.",
+			errCol: 12,
+		},
+		{
+			name:   "Instance without iter",
+			source: "class C {} for (var x in C()) print x;",
+			errMsg: "Can only iterate over lists, maps, strings, and instances with an iter() method.",
+			errCol: 23,
+		},
+		{
+			name:   "Iterator without hasNext",
+			source: "class C { iter() { return this; } next() {} } for (var x in C()) print x;",
+			errMsg: "iter() must return an instance with hasNext() and next() methods.",
+			errCol: 58,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			interpreter := NewInterpreter(&loxerror.Collector{})
+			err := run(t, interpreter, test.source)
+			if test.errMsg != "" {
+				if err == nil || err.Error() != test.errMsg {
+					t.Fatalf("Expected error %q, got %v", test.errMsg, err)
+				}
+				if test.errCol != 0 && err.(*loxerror.RuntimeError).Token.Column != test.errCol {
+					t.Errorf("Expected error at column %d, got %d", test.errCol, err.(*loxerror.RuntimeError).Token.Column)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if val := stringify(global(interpreter, "result")); val != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, val)
+			}
+		})
+	}
+}
+
+func TestResolvedScriptsAreCollected(t *testing.T) {
+	interpreter := NewInterpreter(&loxerror.Collector{})
+	defer runtime.KeepAlive(interpreter)
+	collected := make(chan struct{})
+	func() {
+		reporter := &loxerror.Collector{}
+		source := "{ var a = 1; { print a; } }"
+		statements, _ := parser.NewParser(scanner.NewScanner(source, reporter).ScanTokens(), reporter).Parse()
+		resolver.NewResolver(interpreter, reporter).Resolve(statements)
+		interpreter.SetOutput(io.Discard)
+		interpreter.Interpret(statements)
+		// the resolved reference to a inside the inner block
+		inner := statements[0].(*stmt.Block).Statements[1].(*stmt.Block)
+		variable := inner.Statements[0].(*stmt.Print).Expression.(*expr.Variable)
+		runtime.SetFinalizer(variable, func(*expr.Variable) { close(collected) })
+	}()
+
+	for i := 0; i < 10; i++ {
+		runtime.GC()
+		select {
+		case <-collected:
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Error("Expected the interpreter to let go of the script once it finished")
+}
+
+func TestInterpretRuntimeError(t *testing.T) {
+	reporter := &loxerror.Collector{}
+	interpreter := NewInterpreter(reporter)
+	source := "var a = 1;\nvar b = -\"oops\";\na = 2;"
+	statements, err := parser.NewParser(scanner.NewScanner(source, reporter).ScanTokens(), reporter).Parse()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %v", err)
+	}
+
+	interpreter.Interpret(statements)
+
+	if len(reporter.Diagnostics) != 1 {
+		t.Fatalf("Expected the runtime error to be reported once, got %v", reporter.Diagnostics)
+	}
+	d := reporter.Diagnostics[0]
+	if d.Code != loxerror.CodeRuntime || d.Line != 2 || d.Message != "operand must be a number" {
+		t.Errorf("Expected runtime error on line 2, got %+v", d)
+	}
+	if val := global(interpreter, "a"); val != float64(1) {
+		t.Errorf("Expected execution to stop at the error, but a is %v", val)
+	}
+}
+
+func TestInterpretStackOverflow(t *testing.T) {
+	reporter := &loxerror.Collector{}
+	interpreter := NewInterpreter(reporter)
+	source := "fun f() {\n  f();\n}\nf();"
+	statements, err := parser.NewParser(scanner.NewScanner(source, reporter).ScanTokens(), reporter).Parse()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %v", err)
+	}
+
+	interpreter.Interpret(statements)
+
+	if len(reporter.Diagnostics) != 1 {
+		t.Fatalf("Expected the stack overflow to be reported once, got %v", reporter.Diagnostics)
+	}
+	if d := reporter.Diagnostics[0]; d.Code != loxerror.CodeRuntime || d.String() != "Stack overflow.\n[line 2]" {
+		t.Errorf("Expected a stack overflow on line 2, got %+v", d)
+	}
+	if interpreter.callDepth != 0 {
+		t.Errorf("Expected the call depth to unwind, got %d", interpreter.callDepth)
+	}
+}
@@ -0,0 +1,82 @@
+package interpreter
+
+import (
+	"github.com/joshbochu/golox/loxerror"
+	"github.com/joshbochu/golox/token"
+)
+
+// iterator produces the values a for-in loop visits, returning false once
+// there are none left.
+type iterator func() (interface{}, bool, error)
+
+// iterate returns an iterator over value. Lists are walked by index, so
+// elements pushed during the loop are visited too. Maps yield the keys they
+// held when the loop started and strings yield their characters. An instance
+// is iterable when its class has an iter() method returning an iterator
+// object. Each time round, the iterator's hasNext() method says whether
+// there is another value and its next() method produces it, so an iterator
+// can yield nil like any other value.
+func (i *Interpreter) iterate(in token.Token, value interface{}) (iterator, error) {
+	switch value := value.(type) {
+	case *LoxList:
+		n := 0
+		return func() (interface{}, bool, error) {
+			if n >= len(value.elements) {
+				return nil, false, nil
+			}
+			n++
+			return value.elements[n-1], true, nil
+		}, nil
+	case *LoxMap:
+		keys := append([]interface{}{}, value.keys...)
+		return func() (interface{}, bool, error) {
+			if len(keys) == 0 {
+				return nil, false, nil
+			}
+			key := keys[0]
+			keys = keys[1:]
+			return key, true, nil
+		}, nil
+	case string:
+		runes := []rune(value)
+		return func() (interface{}, bool, error) {
+			if len(runes) == 0 {
+				return nil, false, nil
+			}
+			r := runes[0]
+			runes = runes[1:]
+			return string(r), true, nil
+		}, nil
+	case *LoxInstance:
+		iter, ok := value.class.findMethod("iter")
+		if !ok || iter.Arity() != 0 {
+			break
+		}
+		result, err := iter.Bind(value).Call(i, nil)
+		if err != nil {
+			return nil, err
+		}
+		object, ok := result.(*LoxInstance)
+		if !ok {
+			return nil, loxerror.NewRuntimeError(in, "iter() must return an instance with a next() method.")
+		}
+		hasNext, ok := object.class.findMethod("hasNext")
+		next, nextOk := object.class.findMethod("next")
+		if !ok || !nextOk || hasNext.Arity() != 0 || next.Arity() != 0 {
+			return nil, loxerror.NewRuntimeError(in, "iter() must return an instance with hasNext() and next() methods.")
+		}
+		hasNext, next = hasNext.Bind(object), next.Bind(object)
+		return func() (interface{}, bool, error) {
+			more, err := hasNext.Call(i, nil)
+			if err != nil || !isTruthy(more) {
+				return nil, false, err
+			}
+			element, err := next.Call(i, nil)
+			if err != nil {
+				return nil, false, err
+			}
+			return element, true, nil
+		}, nil
+	}
+	return nil, loxerror.NewRuntimeError(in, "Can only iterate over lists, maps, strings, and instances with an iter() method.")
+}
@@ -0,0 +1,83 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/joshbochu/golox/loxerror"
+	"github.com/joshbochu/golox/token"
+)
+
+// LoxList is an ordered, growable sequence of Lox values.
+type LoxList struct {
+	elements []interface{}
+}
+
+func NewLoxList(elements []interface{}) *LoxList {
+	return &LoxList{elements: elements}
+}
+
+// Elements returns the list's contents, for Go code handed a list.
+func (l *LoxList) Elements() []interface{} {
+	return l.elements
+}
+
+// Get returns the method called name bound to this list. Lists have no
+// fields.
+func (l *LoxList) Get(name token.Token) (interface{}, error) {
+	switch name.Lexeme {
+	case "push":
+		return NewNativeFunction("push", 1, func(arguments []interface{}) (interface{}, error) {
+			l.elements = append(l.elements, arguments[0])
+			return nil, nil
+		}), nil
+	case "pop":
+		return NewNativeFunction("pop", 0, func(arguments []interface{}) (interface{}, error) {
+			if len(l.elements) == 0 {
+				return nil, errors.New("Can't pop from an empty list.")
+			}
+			last := l.elements[len(l.elements)-1]
+			l.elements = l.elements[:len(l.elements)-1]
+			return last, nil
+		}), nil
+	case "len":
+		return NewNativeFunction("len", 0, func(arguments []interface{}) (interface{}, error) {
+			return float64(len(l.elements)), nil
+		}), nil
+	case "slice":
+		return WrapFunc("slice", func(start int, end int) (*LoxList, error) {
+			if start < 0 || end < start || end > len(l.elements) {
+				return nil, fmt.Errorf("Slice [%d, %d) out of range for a list of length %d.", start, end, len(l.elements))
+			}
+			// copy so the slice and the list don't share storage
+			return NewLoxList(append([]interface{}{}, l.elements[start:end]...)), nil
+		})
+	}
+	return nil, loxerror.NewRuntimeError(name, "Undefined property '"+name.Lexeme+"'.")
+}
+
+// index checks that value is a valid index into the list, reporting any
+// problem at the bracket of the index expression.
+func (l *LoxList) index(bracket token.Token, value interface{}) (int, error) {
+	number, ok := value.(float64)
+	if !ok || number != math.Trunc(number) {
+		return 0, loxerror.NewRuntimeError(bracket, "List index must be an integer.")
+	}
+	if number < 0 {
+		return 0, loxerror.NewRuntimeError(bracket, "List index can't be negative.")
+	}
+	if number >= float64(len(l.elements)) {
+		return 0, loxerror.NewRuntimeError(bracket, fmt.Sprintf("List index %s is out of bounds for a list of length %d.", stringify(number), len(l.elements)))
+	}
+	return int(number), nil
+}
+
+func (l *LoxList) String() string {
+	parts := make([]string, len(l.elements))
+	for i, element := range l.elements {
+		parts[i] = stringify(element)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
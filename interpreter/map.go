@@ -0,0 +1,135 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/joshbochu/golox/loxerror"
+	"github.com/joshbochu/golox/token"
+)
+
+// LoxMap maps keys to values, remembering the order keys were first added
+// in so iterating and printing a map is deterministic.
+type LoxMap struct {
+	keys   []interface{}
+	values map[interface{}]interface{}
+}
+
+func NewLoxMap() *LoxMap {
+	return &LoxMap{values: make(map[interface{}]interface{})}
+}
+
+// checkKey reports whether key can be used as a map key. Only values that
+// compare by value can: nil, booleans, numbers other than NaN, and strings.
+// Everything else compares by identity, which would make lookups with an
+// equal but distinct value silently miss.
+func checkKey(key interface{}) error {
+	switch key := key.(type) {
+	case nil, bool, string:
+		return nil
+	case float64:
+		if math.IsNaN(key) {
+			return errors.New("Can't use NaN as a map key.")
+		}
+		return nil
+	case *LoxList:
+		return errors.New("Can't use a list as a map key.")
+	case *LoxMap:
+		return errors.New("Can't use a map as a map key.")
+	case *LoxInstance:
+		return errors.New("Can't use an instance as a map key.")
+	}
+	return fmt.Errorf("Can't use a %s as a map key.", typeOf(key))
+}
+
+// get returns the value stored under key, which must already have been
+// checked with checkKey.
+func (m *LoxMap) get(key interface{}) (interface{}, bool) {
+	value, ok := m.values[key]
+	return value, ok
+}
+
+func (m *LoxMap) set(key interface{}, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *LoxMap) remove(key interface{}) (interface{}, bool) {
+	value, ok := m.values[key]
+	if !ok {
+		return nil, false
+	}
+	delete(m.values, key)
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+	return value, true
+}
+
+// Keys returns the map's keys in insertion order, for Go code handed a map.
+func (m *LoxMap) Keys() []interface{} {
+	return m.keys
+}
+
+// Value returns the value stored under key, for Go code handed a map.
+func (m *LoxMap) Value(key interface{}) (interface{}, bool) {
+	if checkKey(key) != nil {
+		return nil, false
+	}
+	return m.get(key)
+}
+
+// Get returns the method called name bound to this map. Entries are reached
+// by indexing rather than as properties.
+func (m *LoxMap) Get(name token.Token) (interface{}, error) {
+	switch name.Lexeme {
+	case "keys":
+		return NewNativeFunction("keys", 0, func(arguments []interface{}) (interface{}, error) {
+			return NewLoxList(append([]interface{}{}, m.keys...)), nil
+		}), nil
+	case "values":
+		return NewNativeFunction("values", 0, func(arguments []interface{}) (interface{}, error) {
+			values := make([]interface{}, len(m.keys))
+			for i, key := range m.keys {
+				values[i] = m.values[key]
+			}
+			return NewLoxList(values), nil
+		}), nil
+	case "has":
+		return NewNativeFunction("has", 1, func(arguments []interface{}) (interface{}, error) {
+			if err := checkKey(arguments[0]); err != nil {
+				return nil, err
+			}
+			_, ok := m.get(arguments[0])
+			return ok, nil
+		}), nil
+	case "remove":
+		return NewNativeFunction("remove", 1, func(arguments []interface{}) (interface{}, error) {
+			if err := checkKey(arguments[0]); err != nil {
+				return nil, err
+			}
+			value, _ := m.remove(arguments[0])
+			return value, nil
+		}), nil
+	case "len":
+		return NewNativeFunction("len", 0, func(arguments []interface{}) (interface{}, error) {
+			return float64(len(m.keys)), nil
+		}), nil
+	}
+	return nil, loxerror.NewRuntimeError(name, "Undefined property '"+name.Lexeme+"'.")
+}
+
+func (m *LoxMap) String() string {
+	parts := make([]string, len(m.keys))
+	for i, key := range m.keys {
+		parts[i] = stringify(key) + ": " + stringify(m.values[key])
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
@@ -0,0 +1,219 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// NativeFunc implements a native function. It receives and returns Lox
+// values; a non-nil error becomes a runtime error at the call.
+type NativeFunc func(arguments []interface{}) (interface{}, error)
+
+// NativeFunction is a function implemented in Go that Lox code calls like
+// any other.
+type NativeFunction struct {
+	name  string
+	arity int
+	fn    NativeFunc
+}
+
+func NewNativeFunction(name string, arity int, fn NativeFunc) *NativeFunction {
+	return &NativeFunction{name: name, arity: arity, fn: fn}
+}
+
+func (n *NativeFunction) Arity() int {
+	return n.arity
+}
+
+func (n *NativeFunction) Call(interpreter *Interpreter, arguments []interface{}) (interface{}, error) {
+	result, err := n.fn(arguments)
+	if err != nil {
+		// the caller knows where the call is and turns it into a runtime error
+		return nil, &nativeError{err: err}
+	}
+	return result, nil
+}
+
+func (n *NativeFunction) String() string {
+	return "<native fn>"
+}
+
+// nativeError is an error returned by a NativeFunc on its way to becoming a
+// runtime error.
+type nativeError struct {
+	err error
+}
+
+func (e *nativeError) Error() string {
+	return e.err.Error()
+}
+
+// Define binds name to value in the global scope, replacing any existing
+// binding. value must already be a Lox value.
+func (i *Interpreter) Define(name string, value interface{}) {
+	i.globals.Define(name, value)
+}
+
+// DefineNative defines a global native function taking arity arguments.
+func (i *Interpreter) DefineNative(name string, arity int, fn NativeFunc) {
+	i.Define(name, NewNativeFunction(name, arity, fn))
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// WrapFunc turns an ordinary Go function into a native function, converting
+// arguments with FromLox and results with ToLox. fn may return nothing, a
+// value, an error, or a value and an error. Variadic functions aren't
+// supported.
+func WrapFunc(name string, fn interface{}) (*NativeFunction, error) {
+	value := reflect.ValueOf(fn)
+	t := value.Type()
+	if t.Kind() != reflect.Func {
+		return nil, fmt.Errorf("%s: expected a function, got %T", name, fn)
+	}
+	if t.IsVariadic() {
+		return nil, fmt.Errorf("%s: variadic functions aren't supported", name)
+	}
+	returnsError := t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType
+	results := t.NumOut()
+	if returnsError {
+		results--
+	}
+	if results > 1 {
+		return nil, fmt.Errorf("%s: expected at most one result besides an error, got %d", name, results)
+	}
+
+	return NewNativeFunction(name, t.NumIn(), func(arguments []interface{}) (interface{}, error) {
+		in := make([]reflect.Value, len(arguments))
+		for n, argument := range arguments {
+			converted, err := FromLox(argument, t.In(n))
+			if err != nil {
+				return nil, fmt.Errorf("Argument %d to '%s' %v.", n+1, name, err)
+			}
+			in[n] = converted
+		}
+
+		out := value.Call(in)
+		if returnsError {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return nil, err
+			}
+		}
+		if results == 0 {
+			return nil, nil
+		}
+		return ToLox(out[0].Interface())
+	}), nil
+}
+
+// ToLox converts a Go value to the Lox value it stands for: integers and
+// floats become numbers, and nil pointers, maps, slices, and interfaces
+// become nil. Lox values are returned unchanged.
+func ToLox(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case nil, bool, string, float64, LoxCallable, *LoxInstance, *LoxList, *LoxMap:
+		return value, nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+	}
+	return nil, fmt.Errorf("can't convert %T to a Lox value", value)
+}
+
+var errNotInteger = errors.New("must be an integer")
+
+// FromLox converts a Lox value to a Go value of type t. Numbers convert to
+// any integer or float type, integers only when they are whole and in range.
+// nil converts to the zero value of pointers, maps, slices, and interfaces.
+// The error describes the expected type, e.g. "must be a number".
+func FromLox(value interface{}, t reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("must be %s", typeDescription(t))
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(t) {
+		converted := reflect.New(t).Elem()
+		converted.Set(v)
+		return converted, nil
+	}
+
+	number, isNumber := value.(float64)
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if isNumber {
+			converted := reflect.New(t).Elem()
+			if number != math.Trunc(number) || math.Abs(number) >= 1<<63 || converted.OverflowInt(int64(number)) {
+				return reflect.Value{}, errNotInteger
+			}
+			converted.SetInt(int64(number))
+			return converted, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if isNumber {
+			converted := reflect.New(t).Elem()
+			if number != math.Trunc(number) || number < 0 || number >= 1<<64 || converted.OverflowUint(uint64(number)) {
+				return reflect.Value{}, errNotInteger
+			}
+			converted.SetUint(uint64(number))
+			return converted, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if isNumber {
+			return reflect.ValueOf(number).Convert(t), nil
+		}
+	case reflect.String, reflect.Bool:
+		// named types such as `type ID string`
+		if v.Kind() == t.Kind() {
+			return v.Convert(t), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("must be %s", typeDescription(t))
+}
+
+// typeDescription names the kind of Lox value that converts to t.
+func typeDescription(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	}
+	switch t {
+	case reflect.TypeOf((*LoxInstance)(nil)):
+		return "an instance"
+	case reflect.TypeOf((*LoxList)(nil)):
+		return "a list"
+	case reflect.TypeOf((*LoxMap)(nil)):
+		return "a map"
+	case reflect.TypeOf((*LoxCallable)(nil)).Elem():
+		return "a function or class"
+	}
+	return "a " + t.String()
+}
@@ -0,0 +1,178 @@
+package interpreter
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/joshbochu/golox/loxerror"
+)
+
+func TestNativeFunction(t *testing.T) {
+	interpreter := NewInterpreter(&loxerror.Collector{})
+	interpreter.DefineNative("add", 2, func(arguments []interface{}) (interface{}, error) {
+		return arguments[0].(float64) + arguments[1].(float64), nil
+	})
+	interpreter.DefineNative("fail", 0, func(arguments []interface{}) (interface{}, error) {
+		return nil, errors.New("Database unavailable.")
+	})
+
+	if err := run(t, interpreter, "var sum = add(1, 2); var shown = \"${add}\";"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sum := global(interpreter, "sum"); sum != float64(3) {
+		t.Errorf("Expected 3, got %v", sum)
+	}
+	if shown := global(interpreter, "shown"); shown != "<native fn>" {
+		t.Errorf("Expected <native fn>, got %v", shown)
+	}
+
+	tests := []struct {
+		source  string
+		message string
+		line    int
+	}{
+		{"add(1);", "Expected 2 arguments but got 1.", 1},
+		{"var a = 1;\nfail();", "Database unavailable.", 2},
+		{"fun f() {\n  return fail();\n}\nf();", "Database unavailable.", 2},
+	}
+	for _, test := range tests {
+		err := run(t, interpreter, test.source)
+		var runtimeErr *loxerror.RuntimeError
+		if !errors.As(err, &runtimeErr) {
+			t.Fatalf("Expected a runtime error for %q, got %v", test.source, err)
+		}
+		if runtimeErr.Message != test.message || runtimeErr.Token.Line != test.line {
+			t.Errorf("Expected %q on line %d, got %q on line %d", test.message, test.line, runtimeErr.Message, runtimeErr.Token.Line)
+		}
+	}
+}
+
+type userID string
+
+func TestWrapFunc(t *testing.T) {
+	interpreter := NewInterpreter(&loxerror.Collector{})
+	define := func(name string, fn interface{}) {
+		native, err := WrapFunc(name, fn)
+		if err != nil {
+			t.Fatalf("WrapFunc(%s): %v", name, err)
+		}
+		interpreter.Define(name, native)
+	}
+	define("repeat", func(s string, n int) string { return strings.Repeat(s, n) })
+	define("half", func(n float32) float64 { return float64(n / 2) })
+	define("lookup", func(id userID) (int, error) {
+		if id == "" {
+			return 0, errors.New("No such user.")
+		}
+		return len(id), nil
+	})
+	define("name", func(instance *LoxInstance) interface{} {
+		name, _ := instance.Field("name")
+		return name
+	})
+	define("nothing", func(p *int) {})
+	define("negate", func(b bool) bool { return !b })
+
+	if err := run(t, interpreter, `
+		class User { init(name) { this.name = name; } }
+		var a = repeat("ab", 3);
+		var b = half(5);
+		var c = lookup("alice");
+		var d = name(User("bob"));
+		var e = nothing(nil);
+		var f = negate(false);
+	`); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for name, expected := range map[string]interface{}{
+		"a": "ababab", "b": 2.5, "c": float64(5), "d": "bob", "e": nil, "f": true,
+	} {
+		if value := global(interpreter, name); value != expected {
+			t.Errorf("Expected %s to be %v, got %v", name, expected, value)
+		}
+	}
+
+	for source, message := range map[string]string{
+		`lookup("");`:       "No such user.",
+		`repeat("a", 1.5);`: "Argument 2 to 'repeat' must be an integer.",
+		`repeat(1, 1);`:     "Argument 1 to 'repeat' must be a string.",
+		`negate(nil);`:      "Argument 1 to 'negate' must be a boolean.",
+		`name(1);`:          "Argument 1 to 'name' must be an instance.",
+		`half(1, 2);`:       "Expected 1 arguments but got 2.",
+	} {
+		err := run(t, interpreter, source)
+		if err == nil || err.Error() != message {
+			t.Errorf("Expected %q from %s, got %v", message, source, err)
+		}
+	}
+}
+
+func TestWrapFuncRejects(t *testing.T) {
+	for name, fn := range map[string]interface{}{
+		"not a function": 1,
+		"variadic":       func(xs ...int) {},
+		"two results":    func() (int, int) { return 0, 0 },
+	} {
+		if _, err := WrapFunc(name, fn); err == nil {
+			t.Errorf("Expected WrapFunc to reject %s", name)
+		}
+	}
+}
+
+func TestToLox(t *testing.T) {
+	var nilPointer *int
+	tests := []struct {
+		value    interface{}
+		expected interface{}
+	}{
+		{nil, nil},
+		{int8(-3), float64(-3)},
+		{uint64(7), float64(7)},
+		{float32(0.5), 0.5},
+		{userID("x"), "x"},
+		{true, true},
+		{nilPointer, nil},
+	}
+	for _, test := range tests {
+		converted, err := ToLox(test.value)
+		if err != nil || converted != test.expected {
+			t.Errorf("ToLox(%#v) = %v, %v, expected %v", test.value, converted, err, test.expected)
+		}
+	}
+	if _, err := ToLox(struct{}{}); err == nil {
+		t.Error("Expected an error converting a struct")
+	}
+}
+
+func TestFromLox(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		t        reflect.Type
+		expected interface{}
+		err      string
+	}{
+		{float64(3), reflect.TypeOf(0), 3, ""},
+		{float64(255), reflect.TypeOf(uint8(0)), uint8(255), ""},
+		{float64(256), reflect.TypeOf(uint8(0)), nil, "must be an integer"},
+		{float64(-1), reflect.TypeOf(uint(0)), nil, "must be an integer"},
+		{math.Inf(1), reflect.TypeOf(0), nil, "must be an integer"},
+		{"s", reflect.TypeOf((*interface{})(nil)).Elem(), "s", ""},
+		{nil, reflect.TypeOf(""), nil, "must be a string"},
+		{nil, reflect.TypeOf([]int(nil)), []int(nil), ""},
+	}
+	for _, test := range tests {
+		converted, err := FromLox(test.value, test.t)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("FromLox(%v, %v): expected %q, got %v", test.value, test.t, test.err, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(converted.Interface(), test.expected) {
+			t.Errorf("FromLox(%v, %v) = %v, %v, expected %v", test.value, test.t, converted, err, test.expected)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxFractionDigits bounds formatNumber's digits argument.
+const maxFractionDigits = 100
+
+// definePrelude defines the built-in functions every program starts with.
+func (i *Interpreter) definePrelude() {
+	natives := map[string]interface{}{
+		"clock": func() float64 {
+			return float64(time.Now().UnixNano()) / float64(time.Second)
+		},
+
+		"sqrt":  math.Sqrt,
+		"floor": math.Floor,
+		"pow":   math.Pow,
+		"random": func() float64 {
+			return i.random.Float64()
+		},
+		"seed": func(seed int64) {
+			i.random.Seed(seed)
+		},
+
+		"len": func(value interface{}) (int, error) {
+			switch value := value.(type) {
+			case string:
+				return utf8.RuneCountInString(value), nil
+			case *LoxList:
+				return len(value.elements), nil
+			case *LoxMap:
+				return len(value.keys), nil
+			}
+			return 0, errors.New("Argument 1 to 'len' must be a string, a list, or a map.")
+		},
+		"substr": func(s string, start int, length int) (string, error) {
+			runes := []rune(s)
+			if start < 0 || length < 0 || start+length > len(runes) {
+				return "", fmt.Errorf("Substring [%d, %d) out of range for a string of length %d.", start, start+length, len(runes))
+			}
+			return string(runes[start : start+length]), nil
+		},
+		"indexOf": func(s string, substring string) int {
+			index := strings.Index(s, substring)
+			if index == -1 {
+				return -1
+			}
+			return utf8.RuneCountInString(s[:index])
+		},
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"split": func(s string, separator string) *LoxList {
+			parts := strings.Split(s, separator)
+			elements := make([]interface{}, len(parts))
+			for n, part := range parts {
+				elements[n] = part
+			}
+			return NewLoxList(elements)
+		},
+
+		"parseNumber": func(s string) interface{} {
+			number, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return nil
+			}
+			return number
+		},
+		"toString": func(value interface{}) string {
+			return stringify(value)
+		},
+		"formatNumber": func(number float64, digits int) (string, error) {
+			if digits < 0 || digits > maxFractionDigits {
+				return "", fmt.Errorf("Argument 2 to 'formatNumber' must be between 0 and %d.", maxFractionDigits)
+			}
+			return strconv.FormatFloat(number, 'f', digits, 64), nil
+		},
+
+		"typeof": typeOf,
+	}
+
+	for name, fn := range natives {
+		native, err := WrapFunc(name, fn)
+		if err != nil {
+			panic(err)
+		}
+		i.Define(name, native)
+	}
+}
+
+// typeOf names the type of a Lox value.
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case *LoxClass:
+		return "class"
+	case LoxCallable:
+		return "function"
+	case *LoxInstance:
+		return "instance"
+	case *LoxList:
+		return "list"
+	case *LoxMap:
+		return "map"
+	}
+	return "unknown"
+}
+
+// SetRandomSource replaces the source random() draws from, which is seeded
+// from the clock by default. seed() reseeds whichever source is in use.
+func (i *Interpreter) SetRandomSource(source rand.Source) {
+	i.random = rand.New(source)
+}
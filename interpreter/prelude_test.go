@@ -0,0 +1,60 @@
+package interpreter
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joshbochu/golox/loxerror"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestPrelude runs each script in testdata/prelude and compares what it
+// prints, followed by any runtime error, with the .golden file beside it.
+func TestPrelude(t *testing.T) {
+	scripts, err := filepath.Glob(filepath.Join("testdata", "prelude", "*.lox"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) == 0 {
+		t.Fatal("No scripts found")
+	}
+
+	for _, script := range scripts {
+		name := strings.TrimSuffix(filepath.Base(script), ".lox")
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out strings.Builder
+			interpreter := NewInterpreter(&loxerror.Collector{})
+			interpreter.SetOutput(&out)
+			err = run(t, interpreter, string(source))
+			var runtimeErr *loxerror.RuntimeError
+			if errors.As(err, &runtimeErr) {
+				out.WriteString(runtimeErr.Diagnostic().String() + "\n")
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			golden := strings.TrimSuffix(script, ".lox") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, []byte(out.String()), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != string(expected) {
+				t.Errorf("Expected:\n%s\ngot:\n%s", expected, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package interpreter
+
+// returnValue unwinds a return statement back to the enclosing LoxFunction.Call.
+// It travels up the visitor chain as an error so every statement between the
+// return and the call stops executing, and is never surfaced to the user.
+type returnValue struct {
+	value interface{}
+}
+
+func (r *returnValue) Error() string {
+	return "return outside of function"
+}
@@ -0,0 +1,164 @@
+// Package lox embeds the Lox tree-walking interpreter in Go programs.
+package lox
+
+import (
+	"io"
+	"math/rand"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/joshbochu/golox/interpreter"
+	"github.com/joshbochu/golox/loxerror"
+	"github.com/joshbochu/golox/parser"
+	"github.com/joshbochu/golox/resolver"
+	"github.com/joshbochu/golox/scanner"
+)
+
+// Error is returned when a script fails to scan, parse, resolve, or run. A
+// static error stops the script before it runs and may come with several
+// diagnostics; a runtime error has exactly one.
+type Error struct {
+	Diagnostics []loxerror.Diagnostic
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		messages[i] = d.String()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Runtime runs Lox source. Globals defined by one call are visible to the
+// next, as in the REPL.
+//
+// A Runtime is not safe for concurrent use: its globals, output, and random
+// source are shared by every call, so goroutines must either serialize
+// their calls or use a Runtime each.
+type Runtime struct {
+	interpreter *interpreter.Interpreter
+	stderr      io.Writer
+}
+
+// NewRuntime creates a runtime that prints to os.Stdout and writes
+// diagnostics to os.Stderr.
+func NewRuntime() *Runtime {
+	r := &Runtime{stderr: os.Stderr}
+	// runtime errors come back from Execute rather than through the reporter
+	r.interpreter = interpreter.NewInterpreter(&loxerror.Collector{})
+	return r
+}
+
+// SetStdout redirects the output of print statements.
+func (r *Runtime) SetStdout(w io.Writer) {
+	r.interpreter.SetOutput(w)
+}
+
+// SetStderr redirects diagnostics, which are written as cmd/lox shows them
+// in addition to being returned. Use io.Discard to silence them.
+func (r *Runtime) SetStderr(w io.Writer) {
+	r.stderr = w
+}
+
+// SetRandomSource replaces the source random() draws from, e.g. to make
+// scripts that use it reproducible.
+func (r *Runtime) SetRandomSource(source rand.Source) {
+	r.interpreter.SetRandomSource(source)
+}
+
+// NativeFunc implements a function registered with DefineNative.
+type NativeFunc = interpreter.NativeFunc
+
+// DefineNative defines a global function that takes arity arguments and is
+// implemented by fn. A non-nil error from fn stops the script with a runtime
+// error carrying its message.
+func (r *Runtime) DefineNative(name string, arity int, fn NativeFunc) {
+	r.interpreter.DefineNative(name, arity, fn)
+}
+
+// Define defines a global holding the Lox equivalent of value. Go functions
+// become native functions whose arguments and results are converted to and
+// from their Go types; a function whose last result is an error reports it
+// as a runtime error. Numbers of any Go type become Lox numbers, and Lox
+// instances are passed to Go as *interpreter.LoxInstance.
+func (r *Runtime) Define(name string, value interface{}) error {
+	if reflect.TypeOf(value) != nil && reflect.TypeOf(value).Kind() == reflect.Func {
+		native, err := interpreter.WrapFunc(name, value)
+		if err != nil {
+			return err
+		}
+		r.interpreter.Define(name, native)
+		return nil
+	}
+	converted, err := interpreter.ToLox(value)
+	if err != nil {
+		return err
+	}
+	r.interpreter.Define(name, converted)
+	return nil
+}
+
+// Eval runs source and returns the value of its last statement if that is
+// an expression statement, or nil otherwise. Values are float64, string,
+// bool, nil, or the interpreter's functions, classes, instances, lists, and
+// maps.
+func (r *Runtime) Eval(source string) (interface{}, error) {
+	return r.run("", source)
+}
+
+// RunFile runs the script at path like Eval. Diagnostics name the file.
+func (r *Runtime) RunFile(path string) (interface{}, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.run(path, string(source))
+}
+
+func (r *Runtime) run(file string, source string) (interface{}, error) {
+	reporter := newReporter(r.stderr, file, source)
+
+	tokens := scanner.NewScanner(source, reporter).ScanTokens()
+	statements, _ := parser.NewParser(tokens, reporter).Parse()
+	// scanner errors don't stop the parser, so check what was reported
+	if reporter.HasErrors() {
+		return nil, reporter.err()
+	}
+	resolver.NewResolver(r.interpreter, reporter).Resolve(statements)
+	if reporter.HasErrors() {
+		return nil, reporter.err()
+	}
+
+	value, err := r.interpreter.Execute(statements)
+	if runtimeErr, ok := err.(*loxerror.RuntimeError); ok {
+		reporter.Report(runtimeErr.Diagnostic())
+		return nil, reporter.err()
+	}
+	return value, err
+}
+
+// reporter collects the diagnostics of a single call while printing them.
+type reporter struct {
+	loxerror.Collector
+	file    string
+	printer *loxerror.PrintReporter
+}
+
+func newReporter(w io.Writer, file string, source string) *reporter {
+	printer := loxerror.NewPrintReporter(w)
+	printer.SetSource(file, source)
+	return &reporter{file: file, printer: printer}
+}
+
+func (r *reporter) Report(d loxerror.Diagnostic) {
+	r.printer.Report(d)
+	if d.File == "" {
+		d.File = r.file
+	}
+	r.Collector.Report(d)
+}
+
+func (r *reporter) err() *Error {
+	return &Error{Diagnostics: r.Diagnostics}
+}
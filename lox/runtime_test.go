@@ -0,0 +1,226 @@
+package lox
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joshbochu/golox/loxerror"
+)
+
+func newTestRuntime() (*Runtime, *strings.Builder, *strings.Builder) {
+	var stdout, stderr strings.Builder
+	runtime := NewRuntime()
+	runtime.SetStdout(&stdout)
+	runtime.SetStderr(&stderr)
+	return runtime, &stdout, &stderr
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected interface{}
+		output   string
+	}{
+		{"Expression value", "1 + 2;", float64(3), ""},
+		{"String value", `"a" + "b";`, "ab", ""},
+		{"Last statement isn't an expression", "var a = 1;", nil, ""},
+		{"Print", `print "hi"; true;`, true, "hi\n"},
+		{"Function result", "fun f(x) { return x * 2; } f(21);", float64(42), ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runtime, stdout, _ := newTestRuntime()
+			value, err := runtime.Eval(test.source)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if value != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, value)
+			}
+			if stdout.String() != test.output {
+				t.Errorf("Expected output %q, got %q", test.output, stdout.String())
+			}
+		})
+	}
+}
+
+func TestEvalStatePersists(t *testing.T) {
+	runtime, stdout, _ := newTestRuntime()
+	for _, source := range []string{
+		"var count = 0;",
+		"fun inc() { count = count + 1; return count; }",
+		"inc();",
+		"{ var local = inc(); print local; }",
+	} {
+		if _, err := runtime.Eval(source); err != nil {
+			t.Fatalf("Unexpected error in %q: %v", source, err)
+		}
+	}
+	value, err := runtime.Eval("count;")
+	if err != nil || value != float64(2) {
+		t.Errorf("Expected 2, got %v, %v", value, err)
+	}
+	if stdout.String() != "2\n" {
+		t.Errorf("Expected output %q, got %q", "2\n", stdout.String())
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		code    string
+		message string
+		line    int
+		stderr  string
+	}{
+		{
+			name:    "Scan error",
+			source:  "var a = 1@;",
+			code:    loxerror.CodeScan,
+			message: "Unexpected character.",
+			line:    1,
+			stderr:  "[line 1] Error: Unexpected character.\n    var a = 1@;\n             ^\n",
+		},
+		{
+			name:    "Parse error",
+			source:  "print 1",
+			code:    loxerror.CodeParse,
+			message: "Expect ';' after value.",
+			line:    1,
+		},
+		{
+			name:    "Resolve error",
+			source:  "return 1;",
+			code:    loxerror.CodeResolve,
+			message: "Can't return from top-level code.",
+			line:    1,
+		},
+		{
+			name:    "Runtime error",
+			source:  "print 1;\nprint -nil;",
+			code:    loxerror.CodeRuntime,
+			message: "operand must be a number",
+			line:    2,
+			stderr:  "operand must be a number\n[line 2]\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runtime, _, stderr := newTestRuntime()
+			_, err := runtime.Eval(test.source)
+			var loxErr *Error
+			if !errors.As(err, &loxErr) {
+				t.Fatalf("Expected a *lox.Error, got %v", err)
+			}
+			if len(loxErr.Diagnostics) != 1 {
+				t.Fatalf("Expected 1 diagnostic, got %v", loxErr.Diagnostics)
+			}
+			d := loxErr.Diagnostics[0]
+			if d.Code != test.code || d.Message != test.message || d.Line != test.line {
+				t.Errorf("Expected %s error %q on line %d, got %+v", test.code, test.message, test.line, d)
+			}
+			if test.stderr != "" && stderr.String() != test.stderr {
+				t.Errorf("Expected stderr %q, got %q", test.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestEvalAfterError(t *testing.T) {
+	runtime, _, _ := newTestRuntime()
+	runtime.Eval("var a = 1;")
+	if _, err := runtime.Eval("fun f() { a = 2; return nil + 1; } f();"); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if value, err := runtime.Eval("a;"); err != nil || value != float64(2) {
+		t.Errorf("Expected 2, got %v, %v", value, err)
+	}
+}
+
+func TestRunFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.lox")
+	if err := os.WriteFile(path, []byte("var greeting = \"hello\";\nprint greeting;\nundefined;\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runtime, stdout, _ := newTestRuntime()
+	_, err := runtime.RunFile(path)
+	if stdout.String() != "hello\n" {
+		t.Errorf("Expected output %q, got %q", "hello\n", stdout.String())
+	}
+	var loxErr *Error
+	if !errors.As(err, &loxErr) {
+		t.Fatalf("Expected a *lox.Error, got %v", err)
+	}
+	if d := loxErr.Diagnostics[0]; d.File != path || d.Line != 3 || d.Message != "Undefined variable 'undefined'." {
+		t.Errorf("Unexpected diagnostic %+v", d)
+	}
+
+	// state from the file is still there
+	if value, err := runtime.Eval("greeting;"); err != nil || value != "hello" {
+		t.Errorf("Expected %q, got %v, %v", "hello", value, err)
+	}
+
+	if _, err := runtime.RunFile(filepath.Join(t.TempDir(), "missing.lox")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a not-exist error, got %v", err)
+	}
+}
+
+func TestSetStderrDiscard(t *testing.T) {
+	runtime := NewRuntime()
+	runtime.SetStderr(io.Discard)
+	if _, err := runtime.Eval("print;"); err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestDefine(t *testing.T) {
+	runtime, stdout, _ := newTestRuntime()
+	var logged []string
+	if err := runtime.Define("log", func(message string) { logged = append(logged, message) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := runtime.Define("lookup", func(id int) (string, error) {
+		if id != 1 {
+			return "", errors.New("No user with that id.")
+		}
+		return "ada", nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runtime.Define("version", uint8(3)); err != nil {
+		t.Fatal(err)
+	}
+	runtime.DefineNative("twice", 1, func(arguments []interface{}) (interface{}, error) {
+		return arguments[0].(float64) * 2, nil
+	})
+
+	value, err := runtime.Eval(`log("user " + lookup(1)); print twice(version); lookup(1);`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if value != "ada" || stdout.String() != "6\n" || len(logged) != 1 || logged[0] != "user ada" {
+		t.Errorf("Unexpected result %v, output %q, log %v", value, stdout.String(), logged)
+	}
+
+	_, err = runtime.Eval("print 1;\nlookup(2);")
+	var loxErr *Error
+	if !errors.As(err, &loxErr) {
+		t.Fatalf("Expected a *lox.Error, got %v", err)
+	}
+	if d := loxErr.Diagnostics[0]; d.Code != loxerror.CodeRuntime || d.Line != 2 || d.Message != "No user with that id." {
+		t.Errorf("Unexpected diagnostic %+v", d)
+	}
+
+	if err := runtime.Define("bad", struct{}{}); err == nil {
+		t.Error("Expected an error defining a value with no Lox equivalent")
+	}
+}
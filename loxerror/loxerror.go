@@ -2,7 +2,10 @@ package loxerror
 
 import (
 	"fmt"
-	"os"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/joshbochu/golox/token"
 )
@@ -20,47 +23,261 @@ func (e *RuntimeError) Error() string {
 	return e.Message
 }
 
+// Diagnostic converts the error into a runtime diagnostic at its token.
+func (e *RuntimeError) Diagnostic() Diagnostic {
+	span := Span{Start: e.Token.Start, End: e.Token.End}
+	return AtPosition(e.Token.Line, e.Token.Column, span, CodeRuntime, e.Message)
+}
+
 type ParseError struct {
-	message string
+	Token   token.Token
+	Message string
 }
 
-func NewParseError(message string) *ParseError {
-	return &ParseError{message: message}
+func NewParseError(token token.Token, message string) *ParseError {
+	return &ParseError{Token: token, Message: message}
 }
 
 func (e *ParseError) Error() string {
-	return e.message
+	return e.Message
+}
+
+// ResolveError is a static error found after parsing, such as a misplaced return.
+type ResolveError struct {
+	Token   token.Token
+	Message string
+}
+
+func NewResolveError(token token.Token, message string) *ResolveError {
+	return &ResolveError{Token: token, Message: message}
+}
+
+func (e *ResolveError) Error() string {
+	return e.Message
+}
+
+// CompileError is a limit of the bytecode backend exceeded while compiling,
+// such as too many locals in one function.
+type CompileError struct {
+	Line    int
+	Message string
+}
+
+func NewCompileError(line int, message string) *CompileError {
+	return &CompileError{Line: line, Message: message}
 }
 
-// LoxError is the global instance of the ErrorHandler.
-var LoxError = &ErrorHandler{HadError: false, HadRuntimeError: false}
+func (e *CompileError) Error() string {
+	return e.Message
+}
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "Warning"
+	default:
+		return "Error"
+	}
+}
+
+// Codes identify which phase produced a diagnostic.
+const (
+	CodeScan    = "scan"
+	CodeParse   = "parse"
+	CodeResolve = "resolve"
+	CodeCompile = "compile"
+	CodeRuntime = "runtime"
+)
 
-type ErrorHandler struct {
+// Span is a half-open byte range [Start, End) into the source.
+type Span struct {
+	Start int
+	End   int
+}
+
+// Diagnostic is a single problem found in a Lox program.
+type Diagnostic struct {
+	Severity Severity
+	// File is empty unless whoever reports the diagnostic knows the script's path.
+	File   string
+	Line   int
+	Column int
+	Span   Span
+	// Where describes the offending token for the human-readable form,
+	// e.g. " at 'x'" or " at end". It is empty when there is no token.
+	Where   string
+	Message string
+	Code    string
+}
+
+// AtLine builds an error diagnostic for a position known only by its line.
+func AtLine(line int, code string, message string) Diagnostic {
+	return Diagnostic{
+		Severity: SeverityError,
+		Line:     line,
+		Message:  message,
+		Code:     code,
+	}
+}
+
+// AtPosition builds an error diagnostic for an exact source position.
+func AtPosition(line int, column int, span Span, code string, message string) Diagnostic {
+	d := AtLine(line, code, message)
+	d.Column = column
+	d.Span = span
+	return d
+}
+
+// AtToken builds an error diagnostic pointing at t.
+func AtToken(t token.Token, code string, message string) Diagnostic {
+	d := AtPosition(t.Line, t.Column, Span{Start: t.Start, End: t.End}, code, message)
+	if t.Type == token.EOF {
+		d.Where = " at end"
+	} else {
+		d.Where = " at '" + t.Lexeme + "'"
+	}
+	return d
+}
+
+// Location renders the position as file:line:column, or in the reference
+// implementation's "[line N]" form when the file isn't known.
+func (d Diagnostic) Location() string {
+	if d.File == "" {
+		return fmt.Sprintf("[line %d]", d.Line)
+	}
+	location := d.File + ":" + strconv.Itoa(d.Line)
+	if d.Column > 0 {
+		location += ":" + strconv.Itoa(d.Column)
+	}
+	return location
+}
+
+// String renders the diagnostic on a single line. Runtime errors keep the
+// reference implementation's "message\n[line N]" form.
+func (d Diagnostic) String() string {
+	if d.Code == CodeRuntime {
+		return fmt.Sprintf("%s\n[line %d]", d.Message, d.Line)
+	}
+	if d.File == "" {
+		return fmt.Sprintf("%s %s%s: %s", d.Location(), d.Severity, d.Where, d.Message)
+	}
+	return fmt.Sprintf("%s: %s%s: %s", d.Location(), d.Severity, d.Where, d.Message)
+}
+
+// Reporter receives diagnostics as the scanner, parser, resolver, and
+// interpreter find them.
+type Reporter interface {
+	Report(d Diagnostic)
+}
+
+// Collector is a Reporter that keeps every diagnostic in memory.
+type Collector struct {
+	Diagnostics []Diagnostic
+}
+
+func (c *Collector) Report(d Diagnostic) {
+	c.Diagnostics = append(c.Diagnostics, d)
+}
+
+// HasErrors reports whether any error-severity diagnostic was collected.
+func (c *Collector) HasErrors() bool {
+	for _, d := range c.Diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// PrintReporter writes each diagnostic to w in human-readable form and
+// remembers whether a static or runtime error was seen. Once it is told the
+// source being run, static errors are followed by the offending line with
+// a caret underline.
+type PrintReporter struct {
+	w               io.Writer
+	file            string
+	lines           []string
 	HadError        bool
 	HadRuntimeError bool
 }
 
-func (e *ErrorHandler) report(line int, where string, message string) {
-	fmt.Fprintf(os.Stderr, "[line %d] Error%s: %s\n", line, where, message)
-	e.HadError = true
+func NewPrintReporter(w io.Writer) *PrintReporter {
+	return &PrintReporter{w: w}
 }
 
-// ErrorLine reports an error on a specific line.
-func ErrorLine(line int, message string) {
-	LoxError.report(line, "", message)
+// SetSource tells the reporter which file (empty for the REPL) and source
+// text the following diagnostics refer to.
+func (r *PrintReporter) SetSource(file string, source string) {
+	r.file = file
+	r.lines = strings.Split(source, "\n")
 }
 
-// ErrorToken reports an error at a specific token.
-func ErrorToken(t token.Token, message string) {
-	if t.Type == token.EOF {
-		LoxError.report(t.Line, " at end", message)
+func (r *PrintReporter) Report(d Diagnostic) {
+	if d.File == "" {
+		d.File = r.file
+	}
+	fmt.Fprintln(r.w, d.String())
+	if d.Code != CodeRuntime {
+		r.underline(d)
+	}
+	if d.Severity != SeverityError {
+		return
+	}
+	if d.Code == CodeRuntime {
+		r.HadRuntimeError = true
 	} else {
-		LoxError.report(t.Line, " at '"+t.Lexeme+"'", message)
+		r.HadError = true
+	}
+}
+
+// underline prints the diagnostic's source line with carets under its span.
+// Columns count characters, while the span counts bytes.
+func (r *PrintReporter) underline(d Diagnostic) {
+	if d.Line < 1 || d.Line > len(r.lines) || d.Column < 1 {
+		return
+	}
+	line := strings.TrimRight(r.lines[d.Line-1], "\r")
+	runes := []rune(line)
+	start := d.Column - 1
+	if start > len(runes) {
+		start = len(runes)
+	}
+
+	// keep tabs so the caret lines up with the text above it
+	var padding strings.Builder
+	for _, c := range runes[:start] {
+		if c == '\t' {
+			padding.WriteRune('\t')
+		} else {
+			padding.WriteRune(' ')
+		}
+	}
+
+	// only underline the part of the span on this line, and at least one column
+	rest := string(runes[start:])
+	width := d.Span.End - d.Span.Start
+	if width > len(rest) {
+		width = len(rest)
+	}
+	if width < 0 {
+		width = 0
+	}
+	carets := utf8.RuneCountInString(rest[:width])
+	if carets < 1 {
+		carets = 1
 	}
+	fmt.Fprintf(r.w, "    %s\n    %s%s\n", line, padding.String(), strings.Repeat("^", carets))
 }
 
-// ErrorRuntime sets the HadError flag for runtime errors.
-func ErrorRuntime(error RuntimeError) {
-	fmt.Fprintf(os.Stderr, "%s\n[line %d]", error.Message, error.Token.Line)
-	LoxError.HadError = true
+// Reset clears the error flags, e.g. between REPL lines.
+func (r *PrintReporter) Reset() {
+	r.HadError = false
+	r.HadRuntimeError = false
 }
@@ -0,0 +1,87 @@
+package loxerror
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/joshbochu/golox/token"
+)
+
+func TestDiagnosticString(t *testing.T) {
+	tests := []struct {
+		name       string
+		diagnostic Diagnostic
+		expected   string
+	}{
+		{
+			name:       "At line",
+			diagnostic: AtLine(3, CodeScan, "Unexpected character."),
+			expected:   "[line 3] Error: Unexpected character.",
+		},
+		{
+			name:       "At token",
+			diagnostic: AtToken(token.NewToken(token.IDENTIFIER, "x", nil, 2), CodeParse, "Expect ';' after value."),
+			expected:   "[line 2] Error at 'x': Expect ';' after value.",
+		},
+		{
+			name:       "At end",
+			diagnostic: AtToken(token.NewToken(token.EOF, "", nil, 4), CodeParse, "Expect ';' after value."),
+			expected:   "[line 4] Error at end: Expect ';' after value.",
+		},
+		{
+			name:       "With file",
+			diagnostic: Diagnostic{File: "main.lox", Line: 3, Column: 14, Where: " at 'y'", Message: "Expect ';' after value."},
+			expected:   "main.lox:3:14: Error at 'y': Expect ';' after value.",
+		},
+		{
+			name:       "Runtime",
+			diagnostic: NewRuntimeError(token.NewToken(token.MINUS, "-", nil, 5), "operand must be a number").Diagnostic(),
+			expected:   "operand must be a number\n[line 5]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.diagnostic.String(); got != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestPrintReporter(t *testing.T) {
+	var out strings.Builder
+	reporter := NewPrintReporter(&out)
+
+	reporter.Report(AtLine(1, CodeParse, "bad"))
+	if !reporter.HadError || reporter.HadRuntimeError {
+		t.Errorf("Expected only HadError after a parse error")
+	}
+
+	reporter.Reset()
+	reporter.Report(AtLine(2, CodeRuntime, "worse"))
+	if reporter.HadError || !reporter.HadRuntimeError {
+		t.Errorf("Expected only HadRuntimeError after a runtime error")
+	}
+
+	expected := "[line 1] Error: bad\nworse\n[line 2]\n"
+	if out.String() != expected {
+		t.Errorf("Expected output %q, got %q", expected, out.String())
+	}
+}
+
+func TestPrintReporterUnderline(t *testing.T) {
+	var out strings.Builder
+	reporter := NewPrintReporter(&out)
+	reporter.SetSource("main.lox", "var a = 1;\n\tprint a b;\n")
+
+	// b is at line 2, column 10, bytes [20, 21)
+	reporter.Report(AtToken(token.Token{Type: token.IDENTIFIER, Lexeme: "b", Line: 2, Column: 10, Start: 20, End: 21}, CodeParse, "Expect ';' after value."))
+
+	expected := "main.lox:2:10: Error at 'b': Expect ';' after value.\n" +
+		"    \tprint a b;\n" +
+		"    \t        ^\n"
+	if out.String() != expected {
+		t.Errorf("Expected output %q, got %q", expected, out.String())
+	}
+}
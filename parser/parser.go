@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"errors"
+
 	"github.com/joshbochu/golox/expr"
 	"github.com/joshbochu/golox/loxerror"
 	"github.com/joshbochu/golox/stmt"
@@ -8,40 +10,204 @@ import (
 )
 
 /* Eval Order
-expression     → equality ;
+program        → declaration* EOF ;
+declaration    → classDecl | funDecl | varDecl | statement ;
+classDecl      → "class" IDENTIFIER ( "<" IDENTIFIER )? "{" function* "}" ;
+funDecl        → "fun" function ;
+function       → IDENTIFIER "(" parameters? ")" block ;
+parameters     → IDENTIFIER ( "," IDENTIFIER )* ;
+varDecl        → "var" IDENTIFIER ( "=" expression )? ";" ;
+statement      → exprStmt | forStmt | ifStmt | printStmt | returnStmt | whileStmt | block ;
+exprStmt       → expression ";" ;
+                 (a statement starting with "{" is a block unless the "{" is
+                  followed by NUMBER, STRING, IDENTIFIER, "true", "false", or
+                  "nil" and then ":", which starts a map)
+forStmt        → "for" "(" ( varDecl | exprStmt | ";" ) expression? ";" expression? ")" statement | forInStmt ;
+forInStmt      → "for" "(" "var" IDENTIFIER "in" expression ")" statement ;
+ifStmt         → "if" "(" expression ")" statement ( "else" statement )? ;
+printStmt      → "print" expression ";" ;
+returnStmt     → "return" expression? ";" ;
+whileStmt      → "while" "(" expression ")" statement ;
+block          → "{" declaration* "}" ;
+expression     → assignment ;
+assignment     → ( call "." )? IDENTIFIER "=" assignment | call "[" expression "]" "=" assignment | logic_or ;
+logic_or       → logic_and ( "or" logic_and )* ;
+logic_and      → equality ( "and" equality )* ;
 equality       → comparison ( ( "!=" | "==" ) comparison )* ;
 comparison     → term ( ( ">" | ">=" | "<" | "<=" ) term )* ;
 term           → factor ( ( "-" | "+" ) factor )* ;
 factor         → unary ( ( "/" | "*" ) unary )* ;
-unary          → ( "!" | "-" ) unary | primary ;
-primary        → NUMBER | STRING | "true" | "false" | "nil" | "(" expression ")" ;
+unary          → ( "!" | "-" ) unary | call ;
+call           → primary ( "(" arguments? ")" | "." IDENTIFIER | "[" expression "]" )* ;
+arguments      → expression ( "," expression )* ;
+primary        → NUMBER | STRING | "true" | "false" | "nil" | "this" | "(" expression ")" | IDENTIFIER
+               | "super" "." IDENTIFIER | interpolation | list | map ;
+list           → "[" ( expression ( "," expression )* ","? )? "]" ;
+map            → "{" ( entry ( "," entry )* ","? )? "}" ;
+entry          → expression ":" expression ;
+interpolation  → ( INTERPOLATION expression )+ STRING ;
 */
 
+// maxArguments caps parameter and argument lists, matching the reference implementation.
+const maxArguments = 255
+
 type Parser struct {
 	current int
 	tokens  []token.Token
+	// errs collects every syntax error reported, so parsing can carry on
+	// after one and surface them all together.
+	errs     []error
+	reporter loxerror.Reporter
 }
 
-func NewParser(tokens []token.Token) *Parser {
+func NewParser(tokens []token.Token, reporter loxerror.Reporter) *Parser {
 	return &Parser{
-		current: 0,
-		tokens:  tokens,
+		current:  0,
+		tokens:   tokens,
+		reporter: reporter,
 	}
 }
 
+// Parse parses the whole program, recovering from syntax errors at statement
+// boundaries. The returned error joins every *loxerror.ParseError found; the
+// statements that did parse are returned regardless.
 func (p *Parser) Parse() ([]stmt.Stmt, error) {
 	statements := []stmt.Stmt{}
 	for !p.isAtEnd() {
-		stmt, err := p.statement()
+		stmt, err := p.declaration()
 		if err != nil {
-			return nil, err
+			p.synchronize()
+			continue
 		}
 		statements = append(statements, stmt)
 	}
-	return statements, nil
+	return statements, errors.Join(p.errs...)
+}
+
+// declaration    → classDecl | funDecl | varDecl | statement ;
+func (p *Parser) declaration() (stmt.Stmt, error) {
+	if p.match(token.CLASS) {
+		return p.classDeclaration()
+	}
+	if p.match(token.FUN) {
+		return p.function("function")
+	}
+	if p.match(token.VAR) {
+		return p.varDeclaration()
+	}
+	return p.statement()
+}
+
+// classDecl      → "class" IDENTIFIER ( "<" IDENTIFIER )? "{" function* "}" ;
+func (p *Parser) classDeclaration() (stmt.Stmt, error) {
+	name, err := p.consume(token.IDENTIFIER, "Expect class name.")
+	if err != nil {
+		return nil, err
+	}
+
+	var superclass *expr.Variable
+	if p.match(token.LESS) {
+		if _, err := p.consume(token.IDENTIFIER, "Expect superclass name."); err != nil {
+			return nil, err
+		}
+		superclass = &expr.Variable{Name: p.previous()}
+	}
+
+	if _, err := p.consume(token.LEFT_BRACE, "Expect '{' before class body."); err != nil {
+		return nil, err
+	}
+
+	methods := []*stmt.Function{}
+	for !p.check(token.RIGHT_BRACE) && !p.isAtEnd() {
+		method, err := p.function("method")
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, method)
+	}
+
+	if _, err := p.consume(token.RIGHT_BRACE, "Expect '}' after class body."); err != nil {
+		return nil, err
+	}
+	return &stmt.Class{Name: name, Superclass: superclass, Methods: methods}, nil
+}
+
+// function       → IDENTIFIER "(" parameters? ")" block ;
+// kind names what is being declared in error messages.
+func (p *Parser) function(kind string) (*stmt.Function, error) {
+	name, err := p.consume(token.IDENTIFIER, "Expect "+kind+" name.")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(token.LEFT_PAREN, "Expect '(' after "+kind+" name."); err != nil {
+		return nil, err
+	}
+
+	parameters := []token.Token{}
+	if !p.check(token.RIGHT_PAREN) {
+		for {
+			if len(parameters) == maxArguments {
+				// reported once, but not fatal: the rest still parses
+				p.error(p.peek(), "Can't have more than 255 parameters.")
+			}
+			param, err := p.consume(token.IDENTIFIER, "Expect parameter name.")
+			if err != nil {
+				return nil, err
+			}
+			parameters = append(parameters, param)
+			if !p.match(token.COMMA) {
+				break
+			}
+		}
+	}
+	if _, err := p.consume(token.RIGHT_PAREN, "Expect ')' after parameters."); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.consume(token.LEFT_BRACE, "Expect '{' before "+kind+" body."); err != nil {
+		return nil, err
+	}
+	body, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	return &stmt.Function{Name: name, Params: parameters, Body: body}, nil
+}
+
+// varDecl        → "var" IDENTIFIER ( "=" expression )? ";" ;
+func (p *Parser) varDeclaration() (stmt.Stmt, error) {
+	name, err := p.consume(token.IDENTIFIER, "Expect variable name.")
+	if err != nil {
+		return nil, err
+	}
+
+	var initializer expr.Expr
+	if p.match(token.EQUAL) {
+		initializer, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.consume(token.SEMICOLON, "Expect ';' after variable declaration."); err != nil {
+		return nil, err
+	}
+	return &stmt.Var{Name: name, Initializer: initializer}, nil
 }
 
 func (p *Parser) statement() (stmt.Stmt, error) {
+	if p.match(token.FOR) {
+		return p.forStatement()
+	}
+	if p.match(token.IF) {
+		return p.ifStatement()
+	}
+	if p.match(token.WHILE) {
+		return p.whileStatement()
+	}
+	if p.match(token.RETURN) {
+		return p.returnStatement()
+	}
 	if p.match(token.PRINT) {
 		stmt, err := p.printStatement()
 		if err != nil {
@@ -49,19 +215,198 @@ func (p *Parser) statement() (stmt.Stmt, error) {
 		}
 		return stmt, nil
 	}
-	expr, err := p.expressionStatement() // todo expressionstatemetn
+	if !p.startsMap() && p.match(token.LEFT_BRACE) {
+		statements, err := p.block()
+		if err != nil {
+			return nil, err
+		}
+		return &stmt.Block{Statements: statements}, nil
+	}
+	expr, err := p.expressionStatement()
 	if err != nil {
 		return nil, err
 	}
 	return expr, nil
 }
 
+// forStmt        → "for" "(" ( varDecl | exprStmt | ";" ) expression? ";" expression? ")" statement | forInStmt ;
+// There is no node for the C-style loop in the AST, it is desugared into a
+// while loop wrapped in blocks holding the initializer and increment.
+func (p *Parser) forStatement() (stmt.Stmt, error) {
+	if _, err := p.consume(token.LEFT_PAREN, "Expect '(' after 'for'."); err != nil {
+		return nil, err
+	}
+
+	var initializer stmt.Stmt
+	var err error
+	if p.match(token.SEMICOLON) {
+		initializer = nil
+	} else if p.match(token.VAR) {
+		if p.checkNext(token.IN) {
+			return p.forInStatement()
+		}
+		initializer, err = p.varDeclaration()
+	} else {
+		initializer, err = p.expressionStatement()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var condition expr.Expr
+	if !p.check(token.SEMICOLON) {
+		condition, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.consume(token.SEMICOLON, "Expect ';' after loop condition."); err != nil {
+		return nil, err
+	}
+
+	var increment expr.Expr
+	if !p.check(token.RIGHT_PAREN) {
+		increment, err = p.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.consume(token.RIGHT_PAREN, "Expect ')' after for clauses."); err != nil {
+		return nil, err
+	}
+
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+
+	if increment != nil {
+		body = &stmt.Block{Statements: []stmt.Stmt{body, &stmt.Expression{Expression: increment}}}
+	}
+	if condition == nil {
+		condition = &expr.Literal{Value: true}
+	}
+	body = &stmt.While{Condition: condition, Body: body}
+	if initializer != nil {
+		body = &stmt.Block{Statements: []stmt.Stmt{initializer, body}}
+	}
+	return body, nil
+}
+
+// forInStmt      → "for" "(" "var" IDENTIFIER "in" expression ")" statement ;
+// forStatement has already consumed up to "var" and seen "in" one token ahead.
+func (p *Parser) forInStatement() (stmt.Stmt, error) {
+	name, err := p.consume(token.IDENTIFIER, "Expect variable name.")
+	if err != nil {
+		return nil, err
+	}
+	in := p.advance()
+	iterable, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(token.RIGHT_PAREN, "Expect ')' after for-in clause."); err != nil {
+		return nil, err
+	}
+
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	return &stmt.ForIn{Name: name, In: in, Iterable: iterable, Body: body}, nil
+}
+
+// ifStmt         → "if" "(" expression ")" statement ( "else" statement )? ;
+func (p *Parser) ifStatement() (stmt.Stmt, error) {
+	if _, err := p.consume(token.LEFT_PAREN, "Expect '(' after 'if'."); err != nil {
+		return nil, err
+	}
+	condition, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(token.RIGHT_PAREN, "Expect ')' after if condition."); err != nil {
+		return nil, err
+	}
+
+	thenBranch, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	// the else is claimed eagerly, so it binds to the nearest if
+	var elseBranch stmt.Stmt
+	if p.match(token.ELSE) {
+		elseBranch, err = p.statement()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &stmt.If{Condition: condition, ThenBranch: thenBranch, ElseBranch: elseBranch}, nil
+}
+
+// returnStmt     → "return" expression? ";" ;
+func (p *Parser) returnStatement() (stmt.Stmt, error) {
+	keyword := p.previous()
+	var value expr.Expr
+	if !p.check(token.SEMICOLON) {
+		v, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		value = v
+	}
+	if _, err := p.consume(token.SEMICOLON, "Expect ';' after return value."); err != nil {
+		return nil, err
+	}
+	return &stmt.Return{Keyword: keyword, Value: value}, nil
+}
+
+// whileStmt      → "while" "(" expression ")" statement ;
+func (p *Parser) whileStatement() (stmt.Stmt, error) {
+	if _, err := p.consume(token.LEFT_PAREN, "Expect '(' after 'while'."); err != nil {
+		return nil, err
+	}
+	condition, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.consume(token.RIGHT_PAREN, "Expect ')' after condition."); err != nil {
+		return nil, err
+	}
+	body, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	return &stmt.While{Condition: condition, Body: body}, nil
+}
+
+// block          → "{" declaration* "}" ;
+func (p *Parser) block() ([]stmt.Stmt, error) {
+	statements := []stmt.Stmt{}
+	for !p.check(token.RIGHT_BRACE) && !p.isAtEnd() {
+		stmt, err := p.declaration()
+		if err != nil {
+			// recover inside the block so the closing brace is still matched
+			p.synchronize()
+			continue
+		}
+		statements = append(statements, stmt)
+	}
+
+	if _, err := p.consume(token.RIGHT_BRACE, "Expect '}' after block."); err != nil {
+		return nil, err
+	}
+	return statements, nil
+}
+
 func (p *Parser) printStatement() (stmt.Stmt, error) {
 	value, err := p.expression()
 	if err != nil {
 		return nil, err
 	}
-	p.consume(token.SEMICOLON, "Expect ';' after value.")
+	if _, err := p.consume(token.SEMICOLON, "Expect ';' after value."); err != nil {
+		return nil, err
+	}
 	return &stmt.Print{Expression: value}, nil
 }
 
@@ -70,13 +415,78 @@ func (p *Parser) expressionStatement() (stmt.Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
-	p.consume(token.SEMICOLON, "Expect ';' after expression.")
+	if _, err := p.consume(token.SEMICOLON, "Expect ';' after expression."); err != nil {
+		return nil, err
+	}
 	return &stmt.Expression{Expression: expr}, nil
 }
 
-// expression     → equality ;
+// expression     → assignment ;
 func (p *Parser) expression() (expr.Expr, error) {
-	return p.equality()
+	return p.assignment()
+}
+
+// assignment     → ( call "." )? IDENTIFIER "=" assignment | call "[" expression "]" "=" assignment | logic_or ;
+func (p *Parser) assignment() (expr.Expr, error) {
+	target, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.match(token.EQUAL) {
+		equals := p.previous()
+		value, err := p.assignment()
+		if err != nil {
+			return nil, err
+		}
+
+		switch target := target.(type) {
+		case *expr.Variable:
+			return &expr.Assign{Name: target.Name, Value: value}, nil
+		case *expr.Get:
+			return &expr.Set{Object: target.Object, Name: target.Name, Value: value}, nil
+		case *expr.Index:
+			return &expr.SetIndex{Object: target.Object, Bracket: target.Bracket, Index: target.Index, Value: value}, nil
+		}
+
+		// reported, but the parser isn't confused, so carry on with the target
+		p.error(equals, "Invalid assignment target.")
+	}
+	return target, nil
+}
+
+// logic_or       → logic_and ( "or" logic_and )* ;
+func (p *Parser) or() (expr.Expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.match(token.OR) {
+		operator := p.previous()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &expr.Logical{Left: left, Operator: operator, Right: right}
+	}
+	return left, nil
+}
+
+// logic_and      → equality ( "and" equality )* ;
+func (p *Parser) and() (expr.Expr, error) {
+	left, err := p.equality()
+	if err != nil {
+		return nil, err
+	}
+	for p.match(token.AND) {
+		operator := p.previous()
+		right, err := p.equality()
+		if err != nil {
+			return nil, err
+		}
+		left = &expr.Logical{Left: left, Operator: operator, Right: right}
+	}
+	return left, nil
 }
 
 // equality       → comparison ( ( "!=" | "==" ) comparison )* ;
@@ -147,7 +557,7 @@ func (p *Parser) factor() (expr.Expr, error) {
 	return left, nil
 }
 
-// unary          → ( "!" | "-" ) unary
+// unary          → ( "!" | "-" ) unary | call ;
 func (p *Parser) unary() (expr.Expr, error) {
 	if p.match(token.BANG, token.MINUS) {
 		operator := p.previous()
@@ -157,10 +567,72 @@ func (p *Parser) unary() (expr.Expr, error) {
 		}
 		return &expr.Unary{Operator: operator, Right: right}, nil
 	}
-	return p.primary()
+	return p.call()
+}
+
+// call           → primary ( "(" arguments? ")" | "." IDENTIFIER | "[" expression "]" )* ;
+func (p *Parser) call() (expr.Expr, error) {
+	callee, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if p.match(token.LEFT_PAREN) {
+			callee, err = p.finishCall(callee)
+			if err != nil {
+				return nil, err
+			}
+		} else if p.match(token.DOT) {
+			name, err := p.consume(token.IDENTIFIER, "Expect property name after '.'.")
+			if err != nil {
+				return nil, err
+			}
+			callee = &expr.Get{Object: callee, Name: name}
+		} else if p.match(token.LEFT_BRACKET) {
+			bracket := p.previous()
+			index, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.consume(token.RIGHT_BRACKET, "Expect ']' after index."); err != nil {
+				return nil, err
+			}
+			callee = &expr.Index{Object: callee, Bracket: bracket, Index: index}
+		} else {
+			break
+		}
+	}
+	return callee, nil
 }
 
-// primary        → NUMBER | STRING | "true" | "false" | "nil" | "(" expression ")" ;
+// arguments      → expression ( "," expression )* ;
+func (p *Parser) finishCall(callee expr.Expr) (expr.Expr, error) {
+	arguments := []expr.Expr{}
+	if !p.check(token.RIGHT_PAREN) {
+		for {
+			if len(arguments) == maxArguments {
+				// reported once, but not fatal: the rest still parses
+				p.error(p.peek(), "Can't have more than 255 arguments.")
+			}
+			argument, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			arguments = append(arguments, argument)
+			if !p.match(token.COMMA) {
+				break
+			}
+		}
+	}
+
+	paren, err := p.consume(token.RIGHT_PAREN, "Expect ')' after arguments.")
+	if err != nil {
+		return nil, err
+	}
+	return &expr.Call{Callee: callee, Paren: paren, Arguments: arguments}, nil
+}
+
+// primary        → NUMBER | STRING | "true" | "false" | "nil" | "this" | "(" expression ")" | IDENTIFIER | "super" "." IDENTIFIER | interpolation | list | map ;
 func (p *Parser) primary() (expr.Expr, error) {
 	if p.match(token.FALSE) {
 		return &expr.Literal{Value: false}, nil
@@ -170,18 +642,145 @@ func (p *Parser) primary() (expr.Expr, error) {
 		return &expr.Literal{Value: nil}, nil
 	} else if p.match(token.NUMBER, token.STRING) {
 		return &expr.Literal{Value: p.previous().Literal}, nil
+	} else if p.match(token.INTERPOLATION) {
+		return p.interpolation()
+	} else if p.match(token.SUPER) {
+		keyword := p.previous()
+		if _, err := p.consume(token.DOT, "Expect '.' after 'super'."); err != nil {
+			return nil, err
+		}
+		method, err := p.consume(token.IDENTIFIER, "Expect superclass method name.")
+		if err != nil {
+			return nil, err
+		}
+		return &expr.Super{Keyword: keyword, Method: method}, nil
+	} else if p.match(token.THIS) {
+		return &expr.This{Keyword: p.previous()}, nil
+	} else if p.match(token.IDENTIFIER) {
+		return &expr.Variable{Name: p.previous()}, nil
 	} else if p.match(token.LEFT_PAREN) {
 		expression, err := p.expression()
 		if err != nil {
 			return nil, err
 		}
-		p.consume(token.RIGHT_PAREN, "Expect ')' after expression.")
+		if _, err := p.consume(token.RIGHT_PAREN, "Expect ')' after expression."); err != nil {
+			return nil, err
+		}
 		return &expr.Grouping{Expression: expression}, nil
+	} else if p.match(token.LEFT_BRACKET) {
+		return p.list()
+	} else if p.match(token.LEFT_BRACE) {
+		return p.mapLiteral()
 	}
 
 	return nil, p.error(p.peek(), "Expression Expected")
 }
 
+// list           → "[" ( expression ( "," expression )* ","? )? "]" ;
+func (p *Parser) list() (expr.Expr, error) {
+	bracket := p.previous()
+	elements := []expr.Expr{}
+	for !p.check(token.RIGHT_BRACKET) && !p.isAtEnd() {
+		element, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+		if !p.match(token.COMMA) {
+			break
+		}
+	}
+	if _, err := p.consume(token.RIGHT_BRACKET, "Expect ']' after list elements."); err != nil {
+		return nil, err
+	}
+	return &expr.List{Bracket: bracket, Elements: elements}, nil
+}
+
+// map            → "{" ( entry ( "," entry )* ","? )? "}" ;
+// entry          → expression ":" expression ;
+func (p *Parser) mapLiteral() (expr.Expr, error) {
+	brace := p.previous()
+	keys := []expr.Expr{}
+	values := []expr.Expr{}
+	for !p.check(token.RIGHT_BRACE) && !p.isAtEnd() {
+		key, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.consume(token.COLON, "Expect ':' after map key."); err != nil {
+			return nil, err
+		}
+		value, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+		if !p.match(token.COMMA) {
+			break
+		}
+	}
+	if _, err := p.consume(token.RIGHT_BRACE, "Expect '}' after map entries."); err != nil {
+		return nil, err
+	}
+	return &expr.Map{Brace: brace, Keys: keys, Values: values}, nil
+}
+
+// startsMap reports whether the "{" at the start of a statement opens a map
+// literal rather than a block. Two tokens of lookahead decide it: the map's
+// first key has to be a literal or a variable name followed by ":", which
+// can't begin any statement. Other maps used as statements need parentheses,
+// and "{}" is an empty block.
+func (p *Parser) startsMap() bool {
+	if !p.check(token.LEFT_BRACE) || p.current+2 >= len(p.tokens) {
+		return false
+	}
+	switch p.tokens[p.current+1].Type {
+	case token.NUMBER, token.STRING, token.IDENTIFIER, token.TRUE, token.FALSE, token.NIL:
+		return p.tokens[p.current+2].Type == token.COLON
+	}
+	return false
+}
+
+// interpolation  → ( INTERPOLATION expression )+ STRING ;
+// The string is desugared into a chain of concatenations, with each embedded
+// expression wrapped in a Stringify so values of any type can be spliced in.
+func (p *Parser) interpolation() (expr.Expr, error) {
+	start := p.previous()
+	parts := []expr.Expr{}
+	segment := start
+	for {
+		if text := segment.Literal.(string); text != "" {
+			parts = append(parts, &expr.Literal{Value: text})
+		}
+		if segment.Type == token.STRING {
+			break
+		}
+
+		expression, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, &expr.Stringify{Expression: expression})
+
+		if !p.match(token.INTERPOLATION, token.STRING) {
+			return nil, p.error(p.peek(), "Expect '}' after interpolated expression.")
+		}
+		segment = p.previous()
+	}
+
+	if len(parts) == 0 {
+		return &expr.Literal{Value: ""}, nil
+	}
+	// the + operators point at the opening quote of the string literal
+	plus := token.Token{Type: token.PLUS, Lexeme: "+", Line: start.Line, Column: start.Column, Start: start.Start, End: start.Start + 1}
+	result := parts[0]
+	for _, part := range parts[1:] {
+		result = &expr.Binary{Left: result, Operator: plus, Right: part}
+	}
+	return result, nil
+}
+
 func (p *Parser) consume(tokenType token.TokenType, messsage string) (token.Token, error) {
 	if p.check(tokenType) {
 		return p.advance(), nil
@@ -190,8 +789,10 @@ func (p *Parser) consume(tokenType token.TokenType, messsage string) (token.Toke
 }
 
 func (p *Parser) error(token token.Token, message string) error {
-	loxerror.ErrorToken(token, message)
-	return loxerror.NewParseError(message)
+	p.reporter.Report(loxerror.AtToken(token, loxerror.CodeParse, message))
+	err := loxerror.NewParseError(token, message)
+	p.errs = append(p.errs, err)
+	return err
 }
 
 func (p *Parser) match(tokenTypes ...token.TokenType) bool {
@@ -204,6 +805,14 @@ func (p *Parser) match(tokenTypes ...token.TokenType) bool {
 	return false
 }
 
+// checkNext looks one token past the current one.
+func (p *Parser) checkNext(tokenType token.TokenType) bool {
+	if p.isAtEnd() {
+		return false
+	}
+	return p.tokens[p.current+1].Type == tokenType
+}
+
 func (p *Parser) check(tokenType token.TokenType) bool {
 	if p.isAtEnd() {
 		return false
@@ -230,7 +839,8 @@ func (p *Parser) previous() token.Token {
 	return p.tokens[p.current-1]
 }
 
-// TODO still
+// synchronize discards tokens until it reaches what is probably the start of
+// the next statement, so one mistake doesn't cascade into bogus errors.
 func (p *Parser) synchronize() {
 	p.advance()
 
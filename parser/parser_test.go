@@ -1,11 +1,15 @@
 package parser
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/joshbochu/golox/expr"
+	"github.com/joshbochu/golox/loxerror"
 	"github.com/joshbochu/golox/scanner"
+	"github.com/joshbochu/golox/stmt"
 	"github.com/joshbochu/golox/token"
 )
 
@@ -18,65 +22,65 @@ func TestParser(t *testing.T) {
 	}{
 		{
 			name:     "Unary operation",
-			source:   "-5",
-			expected: &expr.Unary{Operator: token.NewToken(token.MINUS, "-", nil, 1), Right: &expr.Literal{Value: float64(5)}},
+			source:   "-5;",
+			expected: &expr.Unary{Operator: tok(token.MINUS, "-", 1), Right: &expr.Literal{Value: float64(5)}},
 		},
 		{
 			name:     "Binary operation",
-			source:   "5 + 3",
-			expected: &expr.Binary{Left: &expr.Literal{Value: float64(5)}, Operator: token.NewToken(token.PLUS, "+", nil, 1), Right: &expr.Literal{Value: float64(3)}},
+			source:   "5 + 3;",
+			expected: &expr.Binary{Left: &expr.Literal{Value: float64(5)}, Operator: tok(token.PLUS, "+", 3), Right: &expr.Literal{Value: float64(3)}},
 		},
 		{
 			name:     "Grouping",
-			source:   "(5 + 3)",
-			expected: &expr.Grouping{Expression: &expr.Binary{Left: &expr.Literal{Value: float64(5)}, Operator: token.NewToken(token.PLUS, "+", nil, 1), Right: &expr.Literal{Value: float64(3)}}},
+			source:   "(5 + 3);",
+			expected: &expr.Grouping{Expression: &expr.Binary{Left: &expr.Literal{Value: float64(5)}, Operator: tok(token.PLUS, "+", 4), Right: &expr.Literal{Value: float64(3)}}},
 		},
 		{
 			name:     "String Literal",
-			source:   "\"Hello\"",
+			source:   "\"Hello\";",
 			expected: &expr.Literal{Value: "Hello"},
 		},
 		{
 			name:     "Number Literal",
-			source:   "42",
+			source:   "42;",
 			expected: &expr.Literal{Value: float64(42)},
 		},
 		{
 			name:     "Boolean Literal",
-			source:   "true",
+			source:   "true;",
 			expected: &expr.Literal{Value: true},
 		},
 		{
 			name:     "Nil Literal",
-			source:   "nil",
+			source:   "nil;",
 			expected: &expr.Literal{Value: nil},
 		},
 		{
 			name:   "Nested Binary Operations",
-			source: "4 + 5 * 3 - 2",
+			source: "4 + 5 * 3 - 2;",
 			expected: &expr.Binary{
 				Left: &expr.Binary{
 					Left:     &expr.Literal{Value: float64(4)},
-					Operator: token.Token{Type: token.PLUS, Lexeme: "+", Line: 1},
+					Operator: tok(token.PLUS, "+", 3),
 					Right: &expr.Binary{
 						Left:     &expr.Literal{Value: float64(5)},
-						Operator: token.Token{Type: token.STAR, Lexeme: "*", Line: 1},
+						Operator: tok(token.STAR, "*", 7),
 						Right:    &expr.Literal{Value: float64(3)},
 					},
 				},
-				Operator: token.Token{Type: token.MINUS, Lexeme: "-", Line: 1},
+				Operator: tok(token.MINUS, "-", 11),
 				Right:    &expr.Literal{Value: float64(2)},
 			},
 		},
 		{
 			name:   "Nested Unary Operations",
-			source: "-!-5",
+			source: "-!-5;",
 			expected: &expr.Unary{
-				Operator: token.Token{Type: token.MINUS, Lexeme: "-", Line: 1},
+				Operator: tok(token.MINUS, "-", 1),
 				Right: &expr.Unary{
-					Operator: token.Token{Type: token.BANG, Lexeme: "!", Line: 1},
+					Operator: tok(token.BANG, "!", 2),
 					Right: &expr.Unary{
-						Operator: token.Token{Type: token.MINUS, Lexeme: "-", Line: 1},
+						Operator: tok(token.MINUS, "-", 3),
 						Right:    &expr.Literal{Value: float64(5)},
 					},
 				},
@@ -84,36 +88,73 @@ func TestParser(t *testing.T) {
 		},
 		{
 			name:   "Mixed Binary and Unary Operations",
-			source: "-5 + 3",
+			source: "-5 + 3;",
 			expected: &expr.Binary{
 				Left: &expr.Unary{
-					Operator: token.Token{Type: token.MINUS, Lexeme: "-", Line: 1},
+					Operator: tok(token.MINUS, "-", 1),
 					Right:    &expr.Literal{Value: float64(5)},
 				},
-				Operator: token.Token{Type: token.PLUS, Lexeme: "+", Line: 1},
+				Operator: tok(token.PLUS, "+", 4),
 				Right:    &expr.Literal{Value: float64(3)},
 			},
 		},
 		{
 			name:   "Grouping with Mixed Operations",
-			source: "-(5 + 3)",
+			source: "-(5 + 3);",
 			expected: &expr.Unary{
-				Operator: token.Token{Type: token.MINUS, Lexeme: "-", Line: 1},
+				Operator: tok(token.MINUS, "-", 1),
 				Right: &expr.Grouping{
 					Expression: &expr.Binary{
 						Left:     &expr.Literal{Value: float64(5)},
-						Operator: token.Token{Type: token.PLUS, Lexeme: "+", Line: 1},
+						Operator: tok(token.PLUS, "+", 5),
 						Right:    &expr.Literal{Value: float64(3)},
 					},
 				},
 			},
 		},
+		{
+			name:   "List literal",
+			source: "[1, \"a\",];",
+			expected: &expr.List{
+				Bracket:  tok(token.LEFT_BRACKET, "[", 1),
+				Elements: []expr.Expr{&expr.Literal{Value: float64(1)}, &expr.Literal{Value: "a"}},
+			},
+		},
+		{
+			name:     "Empty list",
+			source:   "[];",
+			expected: &expr.List{Bracket: tok(token.LEFT_BRACKET, "[", 1), Elements: []expr.Expr{}},
+		},
+		{
+			name:   "Chained index",
+			source: "xs[0][1];",
+			expected: &expr.Index{
+				Object: &expr.Index{
+					Object:  &expr.Variable{Name: tok(token.IDENTIFIER, "xs", 1)},
+					Bracket: tok(token.LEFT_BRACKET, "[", 3),
+					Index:   &expr.Literal{Value: float64(0)},
+				},
+				Bracket: tok(token.LEFT_BRACKET, "[", 6),
+				Index:   &expr.Literal{Value: float64(1)},
+			},
+		},
+		{
+			name:   "Index assignment",
+			source: "xs[0] = 1;",
+			expected: &expr.SetIndex{
+				Object:  &expr.Variable{Name: tok(token.IDENTIFIER, "xs", 1)},
+				Bracket: tok(token.LEFT_BRACKET, "[", 3),
+				Index:   &expr.Literal{Value: float64(0)},
+				Value:   &expr.Literal{Value: float64(1)},
+			},
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			tokens := scanner.NewScanner(test.source).ScanTokens()
-			parser := NewParser(tokens)
+			reporter := &loxerror.Collector{}
+			tokens := scanner.NewScanner(test.source, reporter).ScanTokens()
+			parser := NewParser(tokens, reporter)
 			result, err := parser.Parse()
 
 			if test.expectErr {
@@ -126,9 +167,494 @@ func TestParser(t *testing.T) {
 				return
 			}
 
+			expected := []stmt.Stmt{&stmt.Expression{Expression: test.expected}}
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("Expected %v, but got %v", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParserDeclarations(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    string
+		expected  []stmt.Stmt
+		expectErr bool
+	}{
+		{
+			name:   "Var with initializer",
+			source: "var x = 1;",
+			expected: []stmt.Stmt{
+				&stmt.Var{Name: tok(token.IDENTIFIER, "x", 5), Initializer: &expr.Literal{Value: float64(1)}},
+			},
+		},
+		{
+			name:   "Var without initializer",
+			source: "var x;",
+			expected: []stmt.Stmt{
+				&stmt.Var{Name: tok(token.IDENTIFIER, "x", 5)},
+			},
+		},
+		{
+			name:   "Print variable",
+			source: "var x = 1; print x;",
+			expected: []stmt.Stmt{
+				&stmt.Var{Name: tok(token.IDENTIFIER, "x", 5), Initializer: &expr.Literal{Value: float64(1)}},
+				&stmt.Print{Expression: &expr.Variable{Name: tok(token.IDENTIFIER, "x", 18)}},
+			},
+		},
+		{
+			name:   "Assignment is right associative",
+			source: "a = b = 1;",
+			expected: []stmt.Stmt{
+				&stmt.Expression{Expression: &expr.Assign{
+					Name: tok(token.IDENTIFIER, "a", 1),
+					Value: &expr.Assign{
+						Name:  tok(token.IDENTIFIER, "b", 5),
+						Value: &expr.Literal{Value: float64(1)},
+					},
+				}},
+			},
+		},
+		{
+			name:      "Invalid assignment target",
+			source:    "1 + a = 2;",
+			expectErr: true,
+		},
+		{
+			name:   "Block",
+			source: "{ var x = 1; print x; }",
+			expected: []stmt.Stmt{
+				&stmt.Block{Statements: []stmt.Stmt{
+					&stmt.Var{Name: tok(token.IDENTIFIER, "x", 7), Initializer: &expr.Literal{Value: float64(1)}},
+					&stmt.Print{Expression: &expr.Variable{Name: tok(token.IDENTIFIER, "x", 20)}},
+				}},
+			},
+		},
+		{
+			name:      "Unterminated block",
+			source:    "{ var x = 1;",
+			expectErr: true,
+		},
+		{
+			name:   "Dangling else binds to nearest if",
+			source: "if (a) if (b) print 1; else print 2;",
+			expected: []stmt.Stmt{
+				&stmt.If{
+					Condition: &expr.Variable{Name: tok(token.IDENTIFIER, "a", 5)},
+					ThenBranch: &stmt.If{
+						Condition:  &expr.Variable{Name: tok(token.IDENTIFIER, "b", 12)},
+						ThenBranch: &stmt.Print{Expression: &expr.Literal{Value: float64(1)}},
+						ElseBranch: &stmt.Print{Expression: &expr.Literal{Value: float64(2)}},
+					},
+				},
+			},
+		},
+		{
+			name:   "For desugars into while",
+			source: "for (var i = 0; i < 2; i = i + 1) print i;",
+			expected: []stmt.Stmt{
+				&stmt.Block{Statements: []stmt.Stmt{
+					&stmt.Var{Name: tok(token.IDENTIFIER, "i", 10), Initializer: &expr.Literal{Value: float64(0)}},
+					&stmt.While{
+						Condition: &expr.Binary{
+							Left:     &expr.Variable{Name: tok(token.IDENTIFIER, "i", 17)},
+							Operator: tok(token.LESS, "<", 19),
+							Right:    &expr.Literal{Value: float64(2)},
+						},
+						Body: &stmt.Block{Statements: []stmt.Stmt{
+							&stmt.Print{Expression: &expr.Variable{Name: tok(token.IDENTIFIER, "i", 41)}},
+							&stmt.Expression{Expression: &expr.Assign{
+								Name: tok(token.IDENTIFIER, "i", 24),
+								Value: &expr.Binary{
+									Left:     &expr.Variable{Name: tok(token.IDENTIFIER, "i", 28)},
+									Operator: tok(token.PLUS, "+", 30),
+									Right:    &expr.Literal{Value: float64(1)},
+								},
+							}},
+						}},
+					},
+				}},
+			},
+		},
+		{
+			name:   "Empty for clauses loop forever",
+			source: "for (;;) print 1;",
+			expected: []stmt.Stmt{
+				&stmt.While{
+					Condition: &expr.Literal{Value: true},
+					Body:      &stmt.Print{Expression: &expr.Literal{Value: float64(1)}},
+				},
+			},
+		},
+		{
+			name:   "For-in",
+			source: "for (var x in xs) print x;",
+			expected: []stmt.Stmt{
+				&stmt.ForIn{
+					Name:     tok(token.IDENTIFIER, "x", 10),
+					In:       tok(token.IN, "in", 12),
+					Iterable: &expr.Variable{Name: tok(token.IDENTIFIER, "xs", 15)},
+					Body:     &stmt.Print{Expression: &expr.Variable{Name: tok(token.IDENTIFIER, "x", 25)}},
+				},
+			},
+		},
+		{
+			name:      "For-in needs a var",
+			source:    "for (x in xs) print x;",
+			expectErr: true,
+		},
+		{
+			name:      "For-in needs a closing paren",
+			source:    "for (var x in xs print x;",
+			expectErr: true,
+		},
+		{
+			name:   "And binds tighter than or",
+			source: "a or b and c;",
+			expected: []stmt.Stmt{
+				&stmt.Expression{Expression: &expr.Logical{
+					Left:     &expr.Variable{Name: tok(token.IDENTIFIER, "a", 1)},
+					Operator: tok(token.OR, "or", 3),
+					Right: &expr.Logical{
+						Left:     &expr.Variable{Name: tok(token.IDENTIFIER, "b", 6)},
+						Operator: tok(token.AND, "and", 8),
+						Right:    &expr.Variable{Name: tok(token.IDENTIFIER, "c", 12)},
+					},
+				}},
+			},
+		},
+		{
+			name:   "Function declaration",
+			source: "fun add(a, b) { return a + b; }",
+			expected: []stmt.Stmt{
+				&stmt.Function{
+					Name:   tok(token.IDENTIFIER, "add", 5),
+					Params: []token.Token{tok(token.IDENTIFIER, "a", 9), tok(token.IDENTIFIER, "b", 12)},
+					Body: []stmt.Stmt{
+						&stmt.Return{
+							Keyword: tok(token.RETURN, "return", 17),
+							Value: &expr.Binary{
+								Left:     &expr.Variable{Name: tok(token.IDENTIFIER, "a", 24)},
+								Operator: tok(token.PLUS, "+", 26),
+								Right:    &expr.Variable{Name: tok(token.IDENTIFIER, "b", 28)},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:   "Curried call",
+			source: "f(1)();",
+			expected: []stmt.Stmt{
+				&stmt.Expression{Expression: &expr.Call{
+					Callee: &expr.Call{
+						Callee:    &expr.Variable{Name: tok(token.IDENTIFIER, "f", 1)},
+						Paren:     tok(token.RIGHT_PAREN, ")", 4),
+						Arguments: []expr.Expr{&expr.Literal{Value: float64(1)}},
+					},
+					Paren:     tok(token.RIGHT_PAREN, ")", 6),
+					Arguments: []expr.Expr{},
+				}},
+			},
+		},
+		{
+			name:   "Class with method",
+			source: "class A { m() { return this; } }",
+			expected: []stmt.Stmt{
+				&stmt.Class{
+					Name: tok(token.IDENTIFIER, "A", 7),
+					Methods: []*stmt.Function{{
+						Name:   tok(token.IDENTIFIER, "m", 11),
+						Params: []token.Token{},
+						Body: []stmt.Stmt{
+							&stmt.Return{
+								Keyword: tok(token.RETURN, "return", 17),
+								Value:   &expr.This{Keyword: tok(token.THIS, "this", 24)},
+							},
+						},
+					}},
+				},
+			},
+		},
+		{
+			name:   "Property set",
+			source: "a.b.c = 1;",
+			expected: []stmt.Stmt{
+				&stmt.Expression{Expression: &expr.Set{
+					Object: &expr.Get{
+						Object: &expr.Variable{Name: tok(token.IDENTIFIER, "a", 1)},
+						Name:   tok(token.IDENTIFIER, "b", 3),
+					},
+					Name:  tok(token.IDENTIFIER, "c", 5),
+					Value: &expr.Literal{Value: float64(1)},
+				}},
+			},
+		},
+		{
+			name:   "String interpolation",
+			source: `"a${x}b${1}";`,
+			expected: []stmt.Stmt{
+				&stmt.Expression{Expression: &expr.Binary{
+					Left: &expr.Binary{
+						Left: &expr.Binary{
+							Left:     &expr.Literal{Value: "a"},
+							Operator: tok(token.PLUS, "+", 1),
+							Right:    &expr.Stringify{Expression: &expr.Variable{Name: tok(token.IDENTIFIER, "x", 5)}},
+						},
+						Operator: tok(token.PLUS, "+", 1),
+						Right:    &expr.Literal{Value: "b"},
+					},
+					Operator: tok(token.PLUS, "+", 1),
+					Right:    &expr.Stringify{Expression: &expr.Literal{Value: float64(1)}},
+				}},
+			},
+		},
+		{
+			name:   "Interpolation only",
+			source: `"${x}";`,
+			expected: []stmt.Stmt{
+				&stmt.Expression{Expression: &expr.Stringify{Expression: &expr.Variable{Name: tok(token.IDENTIFIER, "x", 4)}}},
+			},
+		},
+		{
+			name:      "Missing parameter name",
+			source:    "fun f(a,) {}",
+			expectErr: true,
+		},
+		{
+			name:      "Missing variable name",
+			source:    "var = 1;",
+			expectErr: true,
+		},
+		{
+			name:      "Missing semicolon",
+			source:    "var x = 1",
+			expectErr: true,
+		},
+		{
+			name:   "Map statement",
+			source: "{\"a\": [1], \"b\": {},};",
+			expected: []stmt.Stmt{
+				&stmt.Expression{Expression: &expr.Map{
+					Brace: tok(token.LEFT_BRACE, "{", 1),
+					Keys:  []expr.Expr{&expr.Literal{Value: "a"}, &expr.Literal{Value: "b"}},
+					Values: []expr.Expr{
+						&expr.List{Bracket: tok(token.LEFT_BRACKET, "[", 7), Elements: []expr.Expr{&expr.Literal{Value: float64(1)}}},
+						&expr.Map{Brace: tok(token.LEFT_BRACE, "{", 17), Keys: []expr.Expr{}, Values: []expr.Expr{}},
+					},
+				}},
+			},
+		},
+		{
+			name:   "Empty braces are a block",
+			source: "{}",
+			expected: []stmt.Stmt{
+				&stmt.Block{Statements: []stmt.Stmt{}},
+			},
+		},
+		{
+			name:   "Block holding a map",
+			source: "{ print {1: 2}; }",
+			expected: []stmt.Stmt{
+				&stmt.Block{Statements: []stmt.Stmt{
+					&stmt.Print{Expression: &expr.Map{
+						Brace:  tok(token.LEFT_BRACE, "{", 9),
+						Keys:   []expr.Expr{&expr.Literal{Value: float64(1)}},
+						Values: []expr.Expr{&expr.Literal{Value: float64(2)}},
+					}},
+				}},
+			},
+		},
+		{
+			name:   "Map statement keyed by a variable",
+			source: "{k: 1};",
+			expected: []stmt.Stmt{
+				&stmt.Expression{Expression: &expr.Map{
+					Brace:  tok(token.LEFT_BRACE, "{", 1),
+					Keys:   []expr.Expr{&expr.Variable{Name: tok(token.IDENTIFIER, "k", 2)}},
+					Values: []expr.Expr{&expr.Literal{Value: float64(1)}},
+				}},
+			},
+		},
+		{
+			name:   "Block starting with a grouping",
+			source: "{ (1); }",
+			expected: []stmt.Stmt{
+				&stmt.Block{Statements: []stmt.Stmt{
+					&stmt.Expression{Expression: &expr.Grouping{Expression: &expr.Literal{Value: float64(1)}}},
+				}},
+			},
+		},
+		{
+			name:      "Missing colon",
+			source:    "var m = {\"a\" 1};",
+			expectErr: true,
+		},
+		{
+			name:      "Unclosed list",
+			source:    "[1, 2;",
+			expectErr: true,
+		},
+		{
+			name:      "Missing index",
+			source:    "xs[];",
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reporter := &loxerror.Collector{}
+			tokens := scanner.NewScanner(test.source, reporter).ScanTokens()
+			parser := NewParser(tokens, reporter)
+			result, err := parser.Parse()
+
+			if test.expectErr {
+				if err == nil {
+					t.Errorf("Expected error but got none for source: %v", test.source)
+				}
+				return
+			} else if err != nil {
+				t.Errorf("Didn't expect error for source: %v but got %v", test.source, err)
+				return
+			}
+
 			if !reflect.DeepEqual(result, test.expected) {
 				t.Errorf("Expected %v, but got %v", test.expected, result)
 			}
 		})
 	}
 }
+
+func TestParserRecovery(t *testing.T) {
+	source := `
+		var a = 1
+		print a;
+		{
+			var = 2;
+			print a;
+		}
+		print (a;
+		print a;`
+	reporter := &loxerror.Collector{}
+	statements, err := NewParser(scanner.NewScanner(source, reporter).ScanTokens(), reporter).Parse()
+	if err == nil {
+		t.Fatalf("Expected errors but got none")
+	}
+
+	expected := []struct {
+		line    int
+		message string
+	}{
+		{3, "Expect ';' after variable declaration."},
+		{5, "Expect variable name."},
+		{8, "Expect ')' after expression."},
+	}
+	errs := err.(interface{ Unwrap() []error }).Unwrap()
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d: %v", len(expected), len(errs), err)
+	}
+	for i, e := range expected {
+		parseErr, ok := errs[i].(*loxerror.ParseError)
+		if !ok {
+			t.Fatalf("Expected a *loxerror.ParseError, got %T", errs[i])
+		}
+		if parseErr.Message != e.message || parseErr.Token.Line != e.line {
+			t.Errorf("Expected %q on line %d, got %q on line %d", e.message, e.line, parseErr.Message, parseErr.Token.Line)
+		}
+	}
+
+	if len(reporter.Diagnostics) != len(expected) {
+		t.Errorf("Expected every error to be reported, got %v", reporter.Diagnostics)
+	}
+	for _, d := range reporter.Diagnostics {
+		if d.Code != loxerror.CodeParse || d.Severity != loxerror.SeverityError {
+			t.Errorf("Expected a parse error diagnostic, got %+v", d)
+		}
+	}
+
+	// the first print is swallowed while synchronizing, but the block and
+	// the final print survive
+	if len(statements) != 2 {
+		t.Fatalf("Expected 2 statements to survive recovery, got %d", len(statements))
+	}
+	if block, ok := statements[0].(*stmt.Block); !ok || len(block.Statements) != 1 {
+		t.Errorf("Expected recovery to stay inside the block, got %v", statements[0])
+	}
+}
+
+// tok builds the token the scanner produces for lexeme at column col of a
+// single-line source.
+func tok(tokenType token.TokenType, lexeme string, col int) token.Token {
+	return token.Token{
+		Type:   tokenType,
+		Lexeme: lexeme,
+		Line:   1,
+		Column: col,
+		Start:  col - 1,
+		End:    col - 1 + len(lexeme),
+	}
+}
+
+func TestParserTooManyArguments(t *testing.T) {
+	names := make([]string, 256)
+	for i := range names {
+		names[i] = fmt.Sprintf("a%d", i)
+	}
+	list := strings.Join(names, ", ")
+	source := "fun f(" + list + ") { print 1; }\nf(" + list + ");\nprint 2;"
+	reporter := &loxerror.Collector{}
+	statements, err := NewParser(scanner.NewScanner(source, reporter).ScanTokens(), reporter).Parse()
+	if err == nil {
+		t.Fatalf("Expected errors but got none")
+	}
+
+	expected := []string{"Can't have more than 255 parameters.", "Can't have more than 255 arguments."}
+	if len(reporter.Diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %v", len(expected), reporter.Diagnostics)
+	}
+	for i, message := range expected {
+		if d := reporter.Diagnostics[i]; d.Message != message || d.Line != i+1 {
+			t.Errorf("Expected %q on line %d, got %+v", message, i+1, d)
+		}
+	}
+
+	// neither error abandons the construct it is in
+	if len(statements) != 3 {
+		t.Fatalf("Expected every statement to parse, got %d", len(statements))
+	}
+	if function := statements[0].(*stmt.Function); len(function.Params) != 256 || len(function.Body) != 1 {
+		t.Errorf("Expected the whole function to parse, got %d parameters and %d statements", len(function.Params), len(function.Body))
+	}
+	if call := statements[1].(*stmt.Expression).Expression.(*expr.Call); len(call.Arguments) != 256 {
+		t.Errorf("Expected every argument to parse, got %d", len(call.Arguments))
+	}
+}
+
+func TestParserInvalidAssignmentTarget(t *testing.T) {
+	source := "f(1 = 2, a + b = 3);\nprint 4;"
+	reporter := &loxerror.Collector{}
+	statements, err := NewParser(scanner.NewScanner(source, reporter).ScanTokens(), reporter).Parse()
+	if err == nil {
+		t.Fatalf("Expected errors but got none")
+	}
+
+	// both targets are reported, not just the first
+	if len(reporter.Diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %v", reporter.Diagnostics)
+	}
+	for i, column := range []int{5, 16} {
+		if d := reporter.Diagnostics[i]; d.Message != "Invalid assignment target." || d.Line != 1 || d.Column != column {
+			t.Errorf("Expected an invalid assignment target at column %d, got %+v", column, d)
+		}
+	}
+	if len(statements) != 2 {
+		t.Fatalf("Expected every statement to parse, got %d", len(statements))
+	}
+	if call := statements[0].(*stmt.Expression).Expression.(*expr.Call); len(call.Arguments) != 2 {
+		t.Errorf("Expected both arguments to parse, got %d", len(call.Arguments))
+	}
+}
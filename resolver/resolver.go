@@ -0,0 +1,358 @@
+package resolver
+
+import (
+	"errors"
+
+	"github.com/joshbochu/golox/expr"
+	"github.com/joshbochu/golox/loxerror"
+	"github.com/joshbochu/golox/stmt"
+	"github.com/joshbochu/golox/token"
+)
+
+// Locals receives the scope depth of every local variable reference.
+type Locals interface {
+	Resolve(expr expr.Expr, depth int)
+}
+
+type functionType int
+
+const (
+	functionTypeNone functionType = iota
+	functionTypeFunction
+	functionTypeInitializer
+	functionTypeMethod
+)
+
+type classType int
+
+const (
+	classTypeNone classType = iota
+	classTypeClass
+	classTypeSubclass
+)
+
+// Resolver is a static pass run between parsing and interpreting that binds
+// each variable reference to the scope it was declared in.
+type Resolver struct {
+	locals Locals
+	// scopes is a stack of block scopes; the global scope is not tracked.
+	// A name maps to false while its initializer is being resolved.
+	scopes          []map[string]bool
+	currentFunction functionType
+	currentClass    classType
+	errs            []error
+	reporter        loxerror.Reporter
+}
+
+func NewResolver(locals Locals, reporter loxerror.Reporter) *Resolver {
+	return &Resolver{
+		locals:          locals,
+		reporter:        reporter,
+		scopes:          make([]map[string]bool, 0),
+		currentFunction: functionTypeNone,
+		currentClass:    classTypeNone,
+	}
+}
+
+// Resolve walks statements and returns every static error found, joined.
+func (r *Resolver) Resolve(statements []stmt.Stmt) error {
+	r.resolveStmts(statements)
+	return errors.Join(r.errs...)
+}
+
+func (r *Resolver) resolveStmts(statements []stmt.Stmt) {
+	for _, statement := range statements {
+		r.resolveStmt(statement)
+	}
+}
+
+func (r *Resolver) resolveStmt(statement stmt.Stmt) {
+	statement.Accept(r)
+}
+
+func (r *Resolver) resolveExpr(expression expr.Expr) {
+	expression.Accept(r)
+}
+
+func (r *Resolver) resolveFunction(function *stmt.Function, kind functionType) {
+	enclosingFunction := r.currentFunction
+	r.currentFunction = kind
+
+	r.beginScope()
+	for _, param := range function.Params {
+		r.declare(param)
+		r.define(param)
+	}
+	r.resolveStmts(function.Body)
+	r.endScope()
+
+	r.currentFunction = enclosingFunction
+}
+
+func (r *Resolver) beginScope() {
+	r.scopes = append(r.scopes, make(map[string]bool))
+}
+
+func (r *Resolver) endScope() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+func (r *Resolver) declare(name token.Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+	scope := r.scopes[len(r.scopes)-1]
+	if _, ok := scope[name.Lexeme]; ok {
+		r.error(name, "Already a variable with this name in this scope.")
+	}
+	scope[name.Lexeme] = false
+}
+
+func (r *Resolver) define(name token.Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+	r.scopes[len(r.scopes)-1][name.Lexeme] = true
+}
+
+func (r *Resolver) resolveLocal(expression expr.Expr, name token.Token) {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if _, ok := r.scopes[i][name.Lexeme]; ok {
+			r.locals.Resolve(expression, len(r.scopes)-1-i)
+			return
+		}
+	}
+	// not found, assume it's global
+}
+
+func (r *Resolver) error(name token.Token, message string) {
+	r.reporter.Report(loxerror.AtToken(name, loxerror.CodeResolve, message))
+	r.errs = append(r.errs, loxerror.NewResolveError(name, message))
+}
+
+func (r *Resolver) VisitBlockStmt(stmt *stmt.Block) (interface{}, error) {
+	r.beginScope()
+	r.resolveStmts(stmt.Statements)
+	r.endScope()
+	return nil, nil
+}
+
+func (r *Resolver) VisitClassStmt(stmt *stmt.Class) (interface{}, error) {
+	enclosingClass := r.currentClass
+	r.currentClass = classTypeClass
+
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+
+	if stmt.Superclass != nil {
+		if stmt.Name.Lexeme == stmt.Superclass.Name.Lexeme {
+			r.error(stmt.Superclass.Name, "A class can't inherit from itself.")
+		}
+		r.currentClass = classTypeSubclass
+		r.resolveExpr(stmt.Superclass)
+
+		r.beginScope()
+		r.scopes[len(r.scopes)-1]["super"] = true
+	}
+
+	// methods close over a scope holding "this"
+	r.beginScope()
+	r.scopes[len(r.scopes)-1]["this"] = true
+	for _, method := range stmt.Methods {
+		kind := functionTypeMethod
+		if method.Name.Lexeme == "init" {
+			kind = functionTypeInitializer
+		}
+		r.resolveFunction(method, kind)
+	}
+	r.endScope()
+
+	if stmt.Superclass != nil {
+		r.endScope()
+	}
+
+	r.currentClass = enclosingClass
+	return nil, nil
+}
+
+func (r *Resolver) VisitExpressionStmt(stmt *stmt.Expression) (interface{}, error) {
+	r.resolveExpr(stmt.Expression)
+	return nil, nil
+}
+
+func (r *Resolver) VisitForInStmt(stmt *stmt.ForIn) (interface{}, error) {
+	r.resolveExpr(stmt.Iterable)
+	// the loop variable lives in a scope of its own around the body
+	r.beginScope()
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+	r.resolveStmt(stmt.Body)
+	r.endScope()
+	return nil, nil
+}
+
+func (r *Resolver) VisitFunctionStmt(stmt *stmt.Function) (interface{}, error) {
+	// defined eagerly so the function can refer to itself recursively
+	r.declare(stmt.Name)
+	r.define(stmt.Name)
+	r.resolveFunction(stmt, functionTypeFunction)
+	return nil, nil
+}
+
+func (r *Resolver) VisitIfStmt(stmt *stmt.If) (interface{}, error) {
+	r.resolveExpr(stmt.Condition)
+	r.resolveStmt(stmt.ThenBranch)
+	if stmt.ElseBranch != nil {
+		r.resolveStmt(stmt.ElseBranch)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitPrintStmt(stmt *stmt.Print) (interface{}, error) {
+	r.resolveExpr(stmt.Expression)
+	return nil, nil
+}
+
+func (r *Resolver) VisitReturnStmt(stmt *stmt.Return) (interface{}, error) {
+	if r.currentFunction == functionTypeNone {
+		r.error(stmt.Keyword, "Can't return from top-level code.")
+	}
+	if stmt.Value != nil {
+		if r.currentFunction == functionTypeInitializer {
+			r.error(stmt.Keyword, "Can't return a value from an initializer.")
+		}
+		r.resolveExpr(stmt.Value)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitVarStmt(stmt *stmt.Var) (interface{}, error) {
+	r.declare(stmt.Name)
+	if stmt.Initializer != nil {
+		r.resolveExpr(stmt.Initializer)
+	}
+	r.define(stmt.Name)
+	return nil, nil
+}
+
+func (r *Resolver) VisitWhileStmt(stmt *stmt.While) (interface{}, error) {
+	r.resolveExpr(stmt.Condition)
+	r.resolveStmt(stmt.Body)
+	return nil, nil
+}
+
+func (r *Resolver) VisitAssignExpr(expr *expr.Assign) (interface{}, error) {
+	r.resolveExpr(expr.Value)
+	r.resolveLocal(expr, expr.Name)
+	return nil, nil
+}
+
+func (r *Resolver) VisitBinaryExpr(expr *expr.Binary) (interface{}, error) {
+	r.resolveExpr(expr.Left)
+	r.resolveExpr(expr.Right)
+	return nil, nil
+}
+
+func (r *Resolver) VisitCallExpr(expr *expr.Call) (interface{}, error) {
+	r.resolveExpr(expr.Callee)
+	for _, argument := range expr.Arguments {
+		r.resolveExpr(argument)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitGetExpr(expr *expr.Get) (interface{}, error) {
+	// properties are looked up dynamically, only the object is resolved
+	r.resolveExpr(expr.Object)
+	return nil, nil
+}
+
+func (r *Resolver) VisitGroupingExpr(expr *expr.Grouping) (interface{}, error) {
+	r.resolveExpr(expr.Expression)
+	return nil, nil
+}
+
+func (r *Resolver) VisitIndexExpr(expr *expr.Index) (interface{}, error) {
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Index)
+	return nil, nil
+}
+
+func (r *Resolver) VisitListExpr(expr *expr.List) (interface{}, error) {
+	for _, element := range expr.Elements {
+		r.resolveExpr(element)
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitLiteralExpr(expr *expr.Literal) (interface{}, error) {
+	return nil, nil
+}
+
+func (r *Resolver) VisitLogicalExpr(expr *expr.Logical) (interface{}, error) {
+	r.resolveExpr(expr.Left)
+	r.resolveExpr(expr.Right)
+	return nil, nil
+}
+
+func (r *Resolver) VisitMapExpr(expr *expr.Map) (interface{}, error) {
+	for n, key := range expr.Keys {
+		r.resolveExpr(key)
+		r.resolveExpr(expr.Values[n])
+	}
+	return nil, nil
+}
+
+func (r *Resolver) VisitSetExpr(expr *expr.Set) (interface{}, error) {
+	r.resolveExpr(expr.Value)
+	r.resolveExpr(expr.Object)
+	return nil, nil
+}
+
+func (r *Resolver) VisitSetIndexExpr(expr *expr.SetIndex) (interface{}, error) {
+	r.resolveExpr(expr.Value)
+	r.resolveExpr(expr.Object)
+	r.resolveExpr(expr.Index)
+	return nil, nil
+}
+
+func (r *Resolver) VisitStringifyExpr(expr *expr.Stringify) (interface{}, error) {
+	r.resolveExpr(expr.Expression)
+	return nil, nil
+}
+
+func (r *Resolver) VisitSuperExpr(expr *expr.Super) (interface{}, error) {
+	if r.currentClass == classTypeNone {
+		r.error(expr.Keyword, "Can't use 'super' outside of a class.")
+		return nil, nil
+	} else if r.currentClass != classTypeSubclass {
+		r.error(expr.Keyword, "Can't use 'super' in a class with no superclass.")
+		return nil, nil
+	}
+	r.resolveLocal(expr, expr.Keyword)
+	return nil, nil
+}
+
+func (r *Resolver) VisitThisExpr(expr *expr.This) (interface{}, error) {
+	if r.currentClass == classTypeNone {
+		r.error(expr.Keyword, "Can't use 'this' outside of a class.")
+		return nil, nil
+	}
+	r.resolveLocal(expr, expr.Keyword)
+	return nil, nil
+}
+
+func (r *Resolver) VisitUnaryExpr(expr *expr.Unary) (interface{}, error) {
+	r.resolveExpr(expr.Right)
+	return nil, nil
+}
+
+func (r *Resolver) VisitVariableExpr(expr *expr.Variable) (interface{}, error) {
+	if len(r.scopes) > 0 {
+		if defined, ok := r.scopes[len(r.scopes)-1][expr.Name.Lexeme]; ok && !defined {
+			r.error(expr.Name, "Can't read local variable in its own initializer.")
+		}
+	}
+	r.resolveLocal(expr, expr.Name)
+	return nil, nil
+}
@@ -0,0 +1,161 @@
+package resolver
+
+import (
+	"testing"
+
+	"github.com/joshbochu/golox/expr"
+	"github.com/joshbochu/golox/loxerror"
+	"github.com/joshbochu/golox/parser"
+	"github.com/joshbochu/golox/scanner"
+)
+
+// recorder captures resolved depths keyed by variable name.
+type recorder map[string][]int
+
+func (r recorder) Resolve(e expr.Expr, depth int) {
+	switch e := e.(type) {
+	case *expr.Variable:
+		r[e.Name.Lexeme] = append(r[e.Name.Lexeme], depth)
+	case *expr.Assign:
+		r[e.Name.Lexeme] = append(r[e.Name.Lexeme], depth)
+	}
+}
+
+func resolve(t *testing.T, source string) (recorder, error) {
+	t.Helper()
+	reporter := &loxerror.Collector{}
+	statements, err := parser.NewParser(scanner.NewScanner(source, reporter).ScanTokens(), reporter).Parse()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %v", err)
+	}
+	locals := recorder{}
+	return locals, NewResolver(locals, reporter).Resolve(statements)
+}
+
+func TestResolverDepths(t *testing.T) {
+	locals, err := resolve(t, `
+		var g = 1;
+		print g;
+		{
+			var a = 1;
+			{
+				var b = a;
+				fun f(c) { return a + b + c; }
+			}
+		}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, ok := locals["g"]; ok {
+		t.Errorf("Expected globals to be left unresolved, got %v", locals["g"])
+	}
+	expected := map[string][]int{
+		"a": {1, 2},
+		"b": {1},
+		"c": {0},
+	}
+	for name, depths := range expected {
+		got := locals[name]
+		if len(got) != len(depths) {
+			t.Errorf("Expected %s to resolve at %v, got %v", name, depths, got)
+			continue
+		}
+		for i := range depths {
+			if got[i] != depths[i] {
+				t.Errorf("Expected %s to resolve at %v, got %v", name, depths, got)
+				break
+			}
+		}
+	}
+}
+
+func TestResolverErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		errMsg  string
+		errLine int
+	}{
+		{
+			name:    "Local in its own initializer",
+			source:  "{ var a = 1;\n { var a = a; } }",
+			errMsg:  "Can't read local variable in its own initializer.",
+			errLine: 2,
+		},
+		{
+			name:    "Duplicate local declaration",
+			source:  "fun f() {\n var a = 1;\n var a = 2;\n}",
+			errMsg:  "Already a variable with this name in this scope.",
+			errLine: 3,
+		},
+		{
+			name:    "Duplicate parameter",
+			source:  "fun f(a, a) {}",
+			errMsg:  "Already a variable with this name in this scope.",
+			errLine: 1,
+		},
+		{
+			name:    "This outside of a class",
+			source:  "fun f() {\n return this;\n}",
+			errMsg:  "Can't use 'this' outside of a class.",
+			errLine: 2,
+		},
+		{
+			name:    "Value returned from initializer",
+			source:  "class C {\n init() {\n return 1;\n }\n}",
+			errMsg:  "Can't return a value from an initializer.",
+			errLine: 3,
+		},
+		{
+			name:    "Class inherits from itself",
+			source:  "class A < A {}",
+			errMsg:  "A class can't inherit from itself.",
+			errLine: 1,
+		},
+		{
+			name:    "Super outside of a class",
+			source:  "fun f() {\n super.m();\n}",
+			errMsg:  "Can't use 'super' outside of a class.",
+			errLine: 2,
+		},
+		{
+			name:    "Super without a superclass",
+			source:  "class A {\n m() { super.m(); }\n}",
+			errMsg:  "Can't use 'super' in a class with no superclass.",
+			errLine: 2,
+		},
+		{
+			name:    "Top-level return",
+			source:  "print 1;\nreturn 2;",
+			errMsg:  "Can't return from top-level code.",
+			errLine: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := resolve(t, test.source)
+			if err == nil {
+				t.Fatalf("Expected error %q but got none", test.errMsg)
+			}
+			resolveErr, ok := err.(interface{ Unwrap() []error }).Unwrap()[0].(*loxerror.ResolveError)
+			if !ok {
+				t.Fatalf("Expected a *loxerror.ResolveError, got %T", err)
+			}
+			if resolveErr.Message != test.errMsg {
+				t.Errorf("Expected error %q, got %q", test.errMsg, resolveErr.Message)
+			}
+			if resolveErr.Token.Line != test.errLine {
+				t.Errorf("Expected error on line %d, got %d", test.errLine, resolveErr.Token.Line)
+			}
+		})
+	}
+
+	if _, err := resolve(t, "var a = 1; var a = a;"); err != nil {
+		t.Errorf("Expected global redeclaration to be allowed, got %v", err)
+	}
+	if _, err := resolve(t, "class C { init() { return; } }"); err != nil {
+		t.Errorf("Expected bare return in initializer to be allowed, got %v", err)
+	}
+}
@@ -1,22 +1,40 @@
 package scanner
 
 import (
+	"errors"
 	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/joshbochu/golox/loxerror"
 	"github.com/joshbochu/golox/token"
 )
 
 type Scanner struct {
-	source   string
-	tokens   []token.Token
-	start    int
-	current  int
-	line     int
-	keywords map[string]token.TokenType
+	source  string
+	tokens  []token.Token
+	start   int
+	current int
+	line    int
+	// column is the 1-based column, counted in characters, of the next
+	// character to be scanned.
+	column int
+	// startLine and startColumn locate the first character of the token
+	// being scanned, which may sit on an earlier line than s.line.
+	startLine   int
+	startColumn int
+	// interpolations holds, for each "${" we are inside of, how many
+	// unclosed '{' its expression has opened so far.
+	interpolations []int
+	// preserveComments makes comments come out as COMMENT tokens instead
+	// of being skipped like whitespace.
+	preserveComments bool
+	keywords         map[string]token.TokenType
+	reporter         loxerror.Reporter
 }
 
-func NewScanner(source string) *Scanner {
+func NewScanner(source string, reporter loxerror.Reporter) *Scanner {
 	keywords := map[string]token.TokenType{
 		"and":    token.AND,
 		"class":  token.CLASS,
@@ -25,6 +43,7 @@ func NewScanner(source string) *Scanner {
 		"for":    token.FOR,
 		"fun":    token.FUN,
 		"if":     token.IF,
+		"in":     token.IN,
 		"nil":    token.NIL,
 		"or":     token.OR,
 		"print":  token.PRINT,
@@ -42,99 +61,195 @@ func NewScanner(source string) *Scanner {
 		start:    0,
 		current:  0,
 		line:     1,
+		column:   1,
 		keywords: keywords,
+		reporter: reporter,
 	}
 }
 
+// PreserveComments makes ScanTokens emit a COMMENT token for every comment,
+// for tools such as formatters. The parser doesn't accept them.
+func (s *Scanner) PreserveComments(preserve bool) {
+	s.preserveComments = preserve
+}
+
 func (s *Scanner) ScanTokens() []token.Token {
 	for !s.isAtEnd() {
 		s.start = s.current
+		s.startLine = s.line
+		s.startColumn = s.column
 		s.scanToken()
 	}
-	s.tokens = append(s.tokens, token.Token{Type: token.EOF, Lexeme: "", Literal: nil, Line: s.line})
+	s.tokens = append(s.tokens, token.Token{
+		Type:    token.EOF,
+		Lexeme:  "",
+		Literal: nil,
+		Line:    s.line,
+		Column:  s.column,
+		Start:   s.current,
+		End:     s.current,
+	})
 	return s.tokens
 }
 
+// newline records that a '\n' was just consumed.
+func (s *Scanner) newline() {
+	s.line++
+	s.column = 1
+}
+
 func (s *Scanner) isAtEnd() bool {
 	return s.current >= len(s.source)
 }
 
 func (s *Scanner) scanToken() {
 	switch c := s.advance(); c {
-	case "(":
+	case '(':
 		s.addToken(token.LEFT_PAREN)
-	case ")":
+	case ')':
 		s.addToken(token.RIGHT_PAREN)
-	case "{":
+	case '{':
+		if len(s.interpolations) > 0 {
+			s.interpolations[len(s.interpolations)-1]++
+		}
 		s.addToken(token.LEFT_BRACE)
-	case "}":
+	case '}':
+		if n := len(s.interpolations); n > 0 {
+			if s.interpolations[n-1] == 0 {
+				// closes a "${", pick the string back up after it
+				s.interpolations = s.interpolations[:n-1]
+				s.string()
+				return
+			}
+			s.interpolations[n-1]--
+		}
 		s.addToken(token.RIGHT_BRACE)
-	case ",":
+	case '[':
+		s.addToken(token.LEFT_BRACKET)
+	case ']':
+		s.addToken(token.RIGHT_BRACKET)
+	case ',':
 		s.addToken(token.COMMA)
-	case ".":
+	case ':':
+		s.addToken(token.COLON)
+	case '.':
 		s.addToken(token.DOT)
-	case "-":
+	case '-':
 		s.addToken(token.MINUS)
-	case "+":
+	case '+':
 		s.addToken(token.PLUS)
-	case ";":
+	case ';':
 		s.addToken(token.SEMICOLON)
-	case "*":
+	case '*':
 		s.addToken(token.STAR)
-	case "!":
-		if s.match("=") {
+	case '!':
+		if s.match('=') {
 			s.addToken(token.BANG_EQUAL)
 		} else {
 			s.addToken(token.BANG)
 		}
-	case "=":
-		if s.match("=") {
+	case '=':
+		if s.match('=') {
 			s.addToken(token.EQUAL_EQUAL)
 		} else {
 			s.addToken(token.EQUAL)
 		}
-	case "<":
-		if s.match("=") {
+	case '<':
+		if s.match('=') {
 			s.addToken(token.LESS_EQUAL)
 		} else {
 			s.addToken(token.LESS)
 		}
-	case ">":
-		if s.match("=") {
+	case '>':
+		if s.match('=') {
 			s.addToken(token.GREATER_EQUAL)
 		} else {
 			s.addToken(token.GREATER)
 		}
-	case "/":
+	case '/':
 		// nextIsNonNewLine && nextInRange
-		if s.match(("/")) {
-			for s.peek() != "\n" && !s.isAtEnd() {
+		if s.match('/') {
+			for s.peek() != '\n' && !s.isAtEnd() {
 				s.advance()
 			}
+			s.comment()
+		} else if s.match('*') {
+			s.blockComment()
 		} else {
 			s.addToken(token.SLASH)
 		}
 
-	case "\n":
-		s.line++
-	case " ", "\t", "\r":
+	case '\n':
+		s.newline()
+	case ' ', '\t', '\r':
 		// Ignore whitespace
-	case "\"":
+	case '"':
 		s.string()
 	default:
 		if isDigit(c) {
 			s.number()
 		} else if isAlpha(c) {
 			s.identififer()
+		} else if c == utf8.RuneError && s.current-s.start == 1 {
+			// an invalid byte, advance has already reported it
 		} else {
-			loxerror.ErrorLine(s.line, "Unexpected character.")
+			s.error("Unexpected character.")
+		}
+	}
+}
+
+// blockComment skips a /* ... */ comment, which may contain nested ones.
+func (s *Scanner) blockComment() {
+	depth := 1
+	for depth > 0 {
+		if s.isAtEnd() {
+			s.error("Unterminated block comment.")
+			return
+		}
+		if s.peek() == '/' && s.peekNext() == '*' {
+			s.advance()
+			s.advance()
+			depth++
+		} else if s.peek() == '*' && s.peekNext() == '/' {
+			s.advance()
+			s.advance()
+			depth--
+		} else if s.advance() == '\n' {
+			s.newline()
 		}
 	}
+	s.comment()
 }
 
-func (s *Scanner) advance() string {
-	c := string(s.source[s.current])
-	s.current++
+// comment emits the comment just scanned if comments are being preserved.
+func (s *Scanner) comment() {
+	if s.preserveComments {
+		s.addToken(token.COMMENT)
+	}
+}
+
+// error reports a problem with the token being scanned, located at its start.
+func (s *Scanner) error(message string) {
+	s.errorAt(s.startLine, s.startColumn, s.start, message)
+}
+
+// errorAt reports a problem spanning from the byte offset start, at line and
+// column, up to the current position.
+func (s *Scanner) errorAt(line int, column int, start int, message string) {
+	span := loxerror.Span{Start: start, End: s.current}
+	s.reporter.Report(loxerror.AtPosition(line, column, span, loxerror.CodeScan, message))
+}
+
+// advance consumes the next character. Bytes that aren't valid UTF-8 are
+// reported and consumed one at a time, coming back as utf8.RuneError.
+func (s *Scanner) advance() rune {
+	line, column, start := s.line, s.column, s.current
+	c, size := utf8.DecodeRuneInString(s.source[s.current:])
+	s.current += size
+	s.column++
+	if c == utf8.RuneError && size == 1 {
+		s.errorAt(line, column, start, "Invalid UTF-8 sequence.")
+	}
 	return c
 }
 
@@ -144,88 +259,200 @@ func (s *Scanner) addToken(tokenType token.TokenType) {
 
 func (s *Scanner) addTokenWithLiteral(tokenType token.TokenType, literal interface{}) {
 	text := s.source[s.start:s.current]
-	s.tokens = append(s.tokens, token.Token{Type: tokenType, Lexeme: text, Literal: literal, Line: s.line})
+	s.tokens = append(s.tokens, token.Token{
+		Type:    tokenType,
+		Lexeme:  text,
+		Literal: literal,
+		Line:    s.startLine,
+		Column:  s.startColumn,
+		Start:   s.start,
+		End:     s.current,
+	})
 }
 
-func (s *Scanner) match(expected string) bool {
-	if s.isAtEnd() {
+func (s *Scanner) match(expected rune) bool {
+	if s.isAtEnd() || s.peek() != expected {
 		return false
 	}
-
-	c := string(s.source[s.current])
-	if c != expected {
-		return false
-	}
-
-	s.current++
+	s.advance()
 	return true
 }
 
-func (s *Scanner) peek() string {
+func (s *Scanner) peek() rune {
 	if s.isAtEnd() {
-		return "\x00"
+		return '\x00'
 	}
-	return string(s.source[s.current])
+	c, _ := utf8.DecodeRuneInString(s.source[s.current:])
+	return c
 }
 
+// string scans the rest of a string literal, or of one of its segments
+// following an interpolated expression, processing escape sequences.
 func (s *Scanner) string() {
-	// nextIsNonTerminal && nextInRange
-	for s.peek() != "\"" && !s.isAtEnd() {
-		// nextIsNewLine
-		if s.peek() == "\n" {
-			s.line++
+	var value strings.Builder
+	for !s.isAtEnd() {
+		if s.peek() == '"' {
+			break
+		}
+		if s.peek() == '$' && s.peekNext() == '{' {
+			s.advance()
+			s.advance()
+			s.interpolations = append(s.interpolations, 0)
+			s.addTokenWithLiteral(token.INTERPOLATION, value.String())
+			return
+		}
+
+		line, column, start := s.line, s.column, s.current
+		c := s.advance()
+		switch c {
+		case '\n':
+			s.newline()
+			value.WriteRune(c)
+		case '\\':
+			if escaped, ok := s.escape(); ok {
+				value.WriteRune(escaped)
+			} else {
+				s.errorAt(line, column, start, "Invalid escape sequence.")
+			}
+		default:
+			value.WriteRune(c)
 		}
-		s.advance()
 	}
 
 	if s.isAtEnd() {
-		loxerror.ErrorLine(s.line, "Unterminated string")
+		s.error("Unterminated string.")
+		return
 	}
 
 	// is terminal quote character "
 	s.advance()
 
-	stringLiteral := s.source[s.start+1 : s.current-1]
-	s.addTokenWithLiteral(token.STRING, stringLiteral)
+	s.addTokenWithLiteral(token.STRING, value.String())
+}
+
+// escape consumes the rest of an escape sequence after its backslash and
+// returns the character it stands for.
+func (s *Scanner) escape() (rune, bool) {
+	if s.isAtEnd() {
+		return 0, false
+	}
+	switch c := s.advance(); c {
+	case 'n':
+		return '\n', true
+	case 't':
+		return '\t', true
+	case 'r':
+		return '\r', true
+	case '"', '\\', '$':
+		return c, true
+	case 'u':
+		// \u{1F600}
+		if !s.match('{') {
+			return 0, false
+		}
+		digits := s.current
+		for isHexDigit(s.peek()) {
+			s.advance()
+		}
+		hex := s.source[digits:s.current]
+		if !s.match('}') || len(hex) == 0 || len(hex) > 6 {
+			return 0, false
+		}
+		code, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return 0, false
+		}
+		return rune(code), true
+	}
+	return 0, false
+}
+
+func isHexDigit(c rune) bool {
+	return isDigit(c) || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
 }
 
-func isDigit(c string) bool {
-	return c >= "0" && c <= "9"
+func isDigit(c rune) bool {
+	return c >= '0' && c <= '9'
 }
 
+// maxExactInteger is the largest integer a float64 holds exactly, 2^53.
+const maxExactInteger = 1 << 53
+
+// number scans a number literal: decimal with optional fraction and
+// exponent, or an integer with a 0x, 0b, or 0o prefix. Underscores may
+// separate digits.
 func (s *Scanner) number() {
-	for isDigit(s.peek()) {
+	if s.source[s.start] == '0' && strings.ContainsRune("xXbBoO", s.peek()) {
 		s.advance()
+		for isAlphaNumeric(s.peek()) {
+			s.advance()
+		}
+		num, err := strconv.ParseUint(s.source[s.start:s.current], 0, 64)
+		if err == nil && num > maxExactInteger {
+			err = strconv.ErrRange
+		}
+		s.addNumber(float64(num), err)
+		return
 	}
 
-	if s.peek() == "." && isDigit(s.peekNext()) {
+	s.digits()
+	if s.peek() == '.' && isDigit(s.peekNext()) {
 		// skip .
 		s.advance()
-
-		for isDigit(s.peek()) {
+		s.digits()
+	}
+	if s.peek() == 'e' || s.peek() == 'E' {
+		if next := s.peekNext(); isDigit(next) || next == '+' || next == '-' {
+			s.advance()
 			s.advance()
+			s.digits()
 		}
 	}
 
-	num, _ := strconv.ParseFloat(s.source[s.start:s.current], 64)
+	num, err := strconv.ParseFloat(s.source[s.start:s.current], 64)
+	s.addNumber(num, err)
+}
+
+// digits consumes a run of decimal digits and underscores. Whether the
+// underscores sit between digits is left for strconv to check.
+func (s *Scanner) digits() {
+	for isDigit(s.peek()) || s.peek() == '_' {
+		s.advance()
+	}
+}
+
+// addNumber adds a NUMBER token, reporting err from parsing its lexeme.
+func (s *Scanner) addNumber(num float64, err error) {
+	if errors.Is(err, strconv.ErrRange) {
+		s.error("Number literal out of range.")
+	} else if err != nil {
+		s.error("Invalid number literal.")
+	}
 	s.addTokenWithLiteral(token.NUMBER, num)
 }
 
-func (s *Scanner) peekNext() string {
-	if s.current+1 >= len(s.source) {
-		return "\x00"
+func (s *Scanner) peekNext() rune {
+	if s.isAtEnd() {
+		return '\x00'
 	}
-	return string(s.source[s.current+1])
+	_, size := utf8.DecodeRuneInString(s.source[s.current:])
+	if s.current+size >= len(s.source) {
+		return '\x00'
+	}
+	c, _ := utf8.DecodeRuneInString(s.source[s.current+size:])
+	return c
 }
 
-func isAlpha(c string) bool {
-	isLower := 'a' <= c[0] && c[0] <= 'z'
-	isUpper := 'A' <= c[0] && c[0] <= 'Z'
-	isUnderScore := c[0] == '_'
-	return isLower || isUpper || isUnderScore
+// isAlpha accepts ASCII letters and underscores like the reference
+// implementation, plus any other Unicode letter.
+func isAlpha(c rune) bool {
+	isLower := 'a' <= c && c <= 'z'
+	isUpper := 'A' <= c && c <= 'Z'
+	isUnderScore := c == '_'
+	return isLower || isUpper || isUnderScore || (c > unicode.MaxASCII && unicode.IsLetter(c))
 }
 
-func isAlphaNumeric(c string) bool {
+func isAlphaNumeric(c rune) bool {
 	return isAlpha(c) || isDigit(c)
 }
 
@@ -3,6 +3,7 @@ package scanner
 import (
 	"testing"
 
+	"github.com/joshbochu/golox/loxerror"
 	"github.com/joshbochu/golox/token"
 )
 
@@ -32,11 +33,20 @@ func TestScanner_ScanTokens(t *testing.T) {
 			token.PRINT, token.IDENTIFIER, token.SEMICOLON,
 			token.IDENTIFIER, token.EQUAL, token.IDENTIFIER, token.STAR, token.NUMBER, token.SEMICOLON,
 			token.EOF}},
+		{"List", "[1, 2][0]", []token.TokenType{
+			token.LEFT_BRACKET, token.NUMBER, token.COMMA, token.NUMBER, token.RIGHT_BRACKET,
+			token.LEFT_BRACKET, token.NUMBER, token.RIGHT_BRACKET, token.EOF}},
+		{"For-in", "for (var x in xs)", []token.TokenType{
+			token.FOR, token.LEFT_PAREN, token.VAR, token.IDENTIFIER, token.IN, token.IDENTIFIER, token.RIGHT_PAREN, token.EOF}},
+		{"Map", "{1: 2}", []token.TokenType{token.LEFT_BRACE, token.NUMBER, token.COLON, token.NUMBER, token.RIGHT_BRACE, token.EOF}},
+		{"Block comment", "1 /* 2 */ 3", []token.TokenType{token.NUMBER, token.NUMBER, token.EOF}},
+		{"Nested block comment", "1 /* 2 /* 3 */ 4 */ 5", []token.TokenType{token.NUMBER, token.NUMBER, token.EOF}},
+		{"Block comment after a slash", "1 //* 2 */\n3", []token.TokenType{token.NUMBER, token.NUMBER, token.EOF}},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			scanner := NewScanner(test.source)
+			scanner := NewScanner(test.source, &loxerror.Collector{})
 			tokens := scanner.ScanTokens()
 			for i, tt := range test.tokens {
 				if len(tokens) <= i {
@@ -50,3 +60,286 @@ func TestScanner_ScanTokens(t *testing.T) {
 		})
 	}
 }
+
+func TestScanner_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		messages []string
+		lines    []int
+		columns  []int
+	}{
+		{"Unexpected character", "var a = 1;\n@", []string{"Unexpected character."}, []int{2}, []int{1}},
+		{"Unterminated string reported at its opening quote", "var s = \"abc\ndef", []string{"Unterminated string."}, []int{1}, []int{9}},
+		{"Keeps scanning after an error", "# 1 $", []string{"Unexpected character.", "Unexpected character."}, []int{1, 1}, []int{1, 5}},
+		{"Unterminated block comment reported at its opening", "1;\n  /* a /* b */\n c", []string{"Unterminated block comment."}, []int{2}, []int{3}},
+		{"Misplaced digit separator", "1__000 + 2_", []string{"Invalid number literal.", "Invalid number literal."}, []int{1, 1}, []int{1, 10}},
+		{"Invalid binary digit", "0b102", []string{"Invalid number literal."}, []int{1}, []int{1}},
+		{"Missing exponent digits", "1.5e+;", []string{"Invalid number literal."}, []int{1}, []int{1}},
+		{"Number out of range", "\n1e400", []string{"Number literal out of range."}, []int{2}, []int{1}},
+		{"Integer too large to be exact", "0x20000000000001", []string{"Number literal out of range."}, []int{1}, []int{1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reporter := &loxerror.Collector{}
+			NewScanner(test.source, reporter).ScanTokens()
+			if len(reporter.Diagnostics) != len(test.messages) {
+				t.Fatalf("Expected %d diagnostics, got %v", len(test.messages), reporter.Diagnostics)
+			}
+			for i, d := range reporter.Diagnostics {
+				if d.Message != test.messages[i] || d.Line != test.lines[i] || d.Column != test.columns[i] || d.Code != loxerror.CodeScan {
+					t.Errorf("Expected %q at %d:%d, got %+v", test.messages[i], test.lines[i], test.columns[i], d)
+				}
+			}
+		})
+	}
+}
+
+func TestScanner_Positions(t *testing.T) {
+	source := "var s = \"a\nb\";\n\tprint s;"
+	expected := []struct {
+		lexeme string
+		line   int
+		column int
+		start  int
+		end    int
+	}{
+		{"var", 1, 1, 0, 3},
+		{"s", 1, 5, 4, 5},
+		{"=", 1, 7, 6, 7},
+		{"\"a\nb\"", 1, 9, 8, 13},
+		{";", 2, 3, 13, 14},
+		{"print", 3, 2, 16, 21},
+		{"s", 3, 8, 22, 23},
+		{";", 3, 9, 23, 24},
+		{"", 3, 10, 24, 24},
+	}
+
+	tokens := NewScanner(source, &loxerror.Collector{}).ScanTokens()
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d", len(expected), len(tokens))
+	}
+	for i, e := range expected {
+		tok := tokens[i]
+		if tok.Lexeme != e.lexeme || tok.Line != e.line || tok.Column != e.column || tok.Start != e.start || tok.End != e.end {
+			t.Errorf("Expected %q at %d:%d [%d,%d), got %q at %d:%d [%d,%d)",
+				e.lexeme, e.line, e.column, e.start, e.end, tok.Lexeme, tok.Line, tok.Column, tok.Start, tok.End)
+		}
+		if source[tok.Start:tok.End] != tok.Lexeme {
+			t.Errorf("Expected offsets of %q to slice the source, got %q", tok.Lexeme, source[tok.Start:tok.End])
+		}
+	}
+}
+
+func TestScanner_Unicode(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		tokens  []token.TokenType
+		literal interface{}
+	}{
+		{"Emoji in string", "\"héllo 👋\"", []token.TokenType{token.STRING, token.EOF}, "héllo 👋"},
+		{"Emoji in comment", "// ünïcödé 🎉\n1", []token.TokenType{token.NUMBER, token.EOF}, float64(1)},
+		{"Accented identifier", "var café = 1;", []token.TokenType{token.VAR, token.IDENTIFIER, token.EQUAL, token.NUMBER, token.SEMICOLON, token.EOF}, nil},
+		{"Non-Latin identifier", "π", []token.TokenType{token.IDENTIFIER, token.EOF}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reporter := &loxerror.Collector{}
+			tokens := NewScanner(test.source, reporter).ScanTokens()
+			if len(reporter.Diagnostics) != 0 {
+				t.Fatalf("Unexpected diagnostics: %v", reporter.Diagnostics)
+			}
+			if len(tokens) != len(test.tokens) {
+				t.Fatalf("Expected %d tokens, got %d", len(test.tokens), len(tokens))
+			}
+			for i, tt := range test.tokens {
+				if tokens[i].Type != tt {
+					t.Errorf("Expected token %v but got %v", tt, tokens[i].Type)
+				}
+			}
+			if test.literal != nil && tokens[0].Literal != test.literal {
+				t.Errorf("Expected literal %q, got %q", test.literal, tokens[0].Literal)
+			}
+		})
+	}
+
+	// columns count characters rather than bytes
+	tokens := NewScanner("\"é👋\" café x", &loxerror.Collector{}).ScanTokens()
+	if tokens[1].Lexeme != "café" || tokens[1].Column != 6 {
+		t.Errorf("Expected café at column 6, got %q at %d", tokens[1].Lexeme, tokens[1].Column)
+	}
+	if tokens[2].Column != 11 || tokens[2].Start != 15 {
+		t.Errorf("Expected x at column 11 and byte 15, got column %d and byte %d", tokens[2].Column, tokens[2].Start)
+	}
+}
+
+func TestScanner_InvalidUTF8(t *testing.T) {
+	reporter := &loxerror.Collector{}
+	tokens := NewScanner("var s = \"a\xffb\";\n\xc3", reporter).ScanTokens()
+
+	expected := []struct {
+		line   int
+		column int
+	}{
+		{1, 11},
+		{2, 1},
+	}
+	if len(reporter.Diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %v", len(expected), reporter.Diagnostics)
+	}
+	for i, e := range expected {
+		d := reporter.Diagnostics[i]
+		if d.Message != "Invalid UTF-8 sequence." || d.Line != e.line || d.Column != e.column {
+			t.Errorf("Expected invalid UTF-8 at %d:%d, got %+v", e.line, e.column, d)
+		}
+	}
+	// scanning carries on past the bad bytes
+	if tokens[3].Type != token.STRING || tokens[4].Type != token.SEMICOLON {
+		t.Errorf("Expected the string and semicolon to still be scanned, got %v", tokens)
+	}
+}
+
+func TestScanner_Escapes(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		literal  string
+		messages []string
+		column   int
+	}{
+		{"Newline and tab", `"a\nb\tc"`, "a\nb\tc", nil, 0},
+		{"Quote and backslash", `"say \"hi\" \\ bye"`, `say "hi" \ bye`, nil, 0},
+		{"Unicode escape", `"\u{48}\u{e9}\u{1F600}"`, "Hé😀", nil, 0},
+		{"Escaped interpolation", `"\${x}"`, "${x}", nil, 0},
+		{"Unknown escape", `"a\qb"`, "ab", []string{"Invalid escape sequence."}, 3},
+		{"Unicode escape without braces", `"\u0041"`, "0041", []string{"Invalid escape sequence."}, 2},
+		{"Unicode escape out of range", `"\u{110000}"`, "", []string{"Invalid escape sequence."}, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reporter := &loxerror.Collector{}
+			tokens := NewScanner(test.source, reporter).ScanTokens()
+			if tokens[0].Type != token.STRING || tokens[0].Literal != test.literal {
+				t.Errorf("Expected string %q, got %v %q", test.literal, tokens[0].Type, tokens[0].Literal)
+			}
+			if tokens[0].Lexeme != test.source {
+				t.Errorf("Expected lexeme to keep the raw source %q, got %q", test.source, tokens[0].Lexeme)
+			}
+			if len(reporter.Diagnostics) != len(test.messages) {
+				t.Fatalf("Expected %d diagnostics, got %v", len(test.messages), reporter.Diagnostics)
+			}
+			for i, d := range reporter.Diagnostics {
+				if d.Message != test.messages[i] || d.Column != test.column {
+					t.Errorf("Expected %q at the backslash, got %+v", test.messages[i], d)
+				}
+			}
+		})
+	}
+}
+
+func TestScanner_Interpolation(t *testing.T) {
+	source := `"a ${x} b ${ {} } c"`
+	expected := []struct {
+		tokenType token.TokenType
+		lexeme    string
+		literal   interface{}
+	}{
+		{token.INTERPOLATION, `"a ${`, "a "},
+		{token.IDENTIFIER, "x", nil},
+		{token.INTERPOLATION, `} b ${`, " b "},
+		{token.LEFT_BRACE, "{", nil},
+		{token.RIGHT_BRACE, "}", nil},
+		{token.STRING, `} c"`, " c"},
+		{token.EOF, "", nil},
+	}
+
+	reporter := &loxerror.Collector{}
+	tokens := NewScanner(source, reporter).ScanTokens()
+	if len(reporter.Diagnostics) != 0 {
+		t.Fatalf("Unexpected diagnostics: %v", reporter.Diagnostics)
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d", len(expected), len(tokens))
+	}
+	for i, e := range expected {
+		if tokens[i].Type != e.tokenType || tokens[i].Lexeme != e.lexeme || tokens[i].Literal != e.literal {
+			t.Errorf("Expected %v %q %v, got %v %q %v", e.tokenType, e.lexeme, e.literal, tokens[i].Type, tokens[i].Lexeme, tokens[i].Literal)
+		}
+	}
+}
+
+func TestScanner_BlockCommentLines(t *testing.T) {
+	tokens := NewScanner("/* a\n/* b\n*/ */\nx", &loxerror.Collector{}).ScanTokens()
+	if tokens[0].Type != token.IDENTIFIER || tokens[0].Line != 4 || tokens[0].Column != 1 {
+		t.Errorf("Expected x at 4:1, got %v at %d:%d", tokens[0].Type, tokens[0].Line, tokens[0].Column)
+	}
+}
+
+func TestScanner_PreserveComments(t *testing.T) {
+	source := "// one\nvar /* two\n /* three */ */ x;"
+	expected := []struct {
+		tokenType token.TokenType
+		lexeme    string
+		line      int
+	}{
+		{token.COMMENT, "// one", 1},
+		{token.VAR, "var", 2},
+		{token.COMMENT, "/* two\n /* three */ */", 2},
+		{token.IDENTIFIER, "x", 3},
+		{token.SEMICOLON, ";", 3},
+		{token.EOF, "", 3},
+	}
+
+	scanner := NewScanner(source, &loxerror.Collector{})
+	scanner.PreserveComments(true)
+	tokens := scanner.ScanTokens()
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %d", len(expected), len(tokens))
+	}
+	for i, e := range expected {
+		if tokens[i].Type != e.tokenType || tokens[i].Lexeme != e.lexeme || tokens[i].Line != e.line {
+			t.Errorf("Expected %v %q on line %d, got %v %q on line %d", e.tokenType, e.lexeme, e.line, tokens[i].Type, tokens[i].Lexeme, tokens[i].Line)
+		}
+	}
+}
+
+func TestScanner_Numbers(t *testing.T) {
+	tests := []struct {
+		source string
+		value  float64
+	}{
+		{"123", 123},
+		{"123.456", 123.456},
+		{"1_000_000", 1000000},
+		{"1_000.000_5", 1000.0005},
+		{"1.5e-3", 0.0015},
+		{"2E3", 2000},
+		{"1e+2", 100},
+		{"0x1F", 31},
+		{"0XfF", 255},
+		{"0b1010", 10},
+		{"0o17", 15},
+		{"0xFFFF_FFFF", 4294967295},
+		{"0x20000000000000", 9007199254740992},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			reporter := &loxerror.Collector{}
+			tokens := NewScanner(test.source, reporter).ScanTokens()
+			if len(reporter.Diagnostics) != 0 {
+				t.Fatalf("Unexpected diagnostics: %v", reporter.Diagnostics)
+			}
+			if len(tokens) != 2 || tokens[0].Type != token.NUMBER || tokens[0].Lexeme != test.source {
+				t.Fatalf("Expected a single NUMBER %q, got %v", test.source, tokens)
+			}
+			if tokens[0].Literal != test.value {
+				t.Errorf("Expected %v, got %v", test.value, tokens[0].Literal)
+			}
+		})
+	}
+}
@@ -10,9 +10,42 @@ type Stmt interface {
 }
 
 type StmtVisitor interface {
+	VisitBlockStmt(expr *Block) (interface{}, error)
+	VisitClassStmt(expr *Class) (interface{}, error)
 	VisitExpressionStmt(expr *Expression) (interface{}, error)
+	VisitForInStmt(expr *ForIn) (interface{}, error)
+	VisitFunctionStmt(expr *Function) (interface{}, error)
+	VisitIfStmt(expr *If) (interface{}, error)
 	VisitPrintStmt(expr *Print) (interface{}, error)
+	VisitReturnStmt(expr *Return) (interface{}, error)
 	VisitVarStmt(expr *Var) (interface{}, error)
+	VisitWhileStmt(expr *While) (interface{}, error)
+}
+
+type Block struct {
+	Statements []Stmt
+}
+
+func (e *Block) Accept(visitor StmtVisitor) (interface{}, error) {
+	val, err := visitor.VisitBlockStmt(e)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+type Class struct {
+	Name       token.Token
+	Superclass *expr.Variable
+	Methods    []*Function
+}
+
+func (e *Class) Accept(visitor StmtVisitor) (interface{}, error) {
+	val, err := visitor.VisitClassStmt(e)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
 }
 
 type Expression struct {
@@ -27,6 +60,49 @@ func (e *Expression) Accept(visitor StmtVisitor) (interface{}, error) {
 	return val, nil
 }
 
+type ForIn struct {
+	Name     token.Token
+	In       token.Token
+	Iterable expr.Expr
+	Body     Stmt
+}
+
+func (e *ForIn) Accept(visitor StmtVisitor) (interface{}, error) {
+	val, err := visitor.VisitForInStmt(e)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+type Function struct {
+	Name   token.Token
+	Params []token.Token
+	Body   []Stmt
+}
+
+func (e *Function) Accept(visitor StmtVisitor) (interface{}, error) {
+	val, err := visitor.VisitFunctionStmt(e)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+type If struct {
+	Condition  expr.Expr
+	ThenBranch Stmt
+	ElseBranch Stmt
+}
+
+func (e *If) Accept(visitor StmtVisitor) (interface{}, error) {
+	val, err := visitor.VisitIfStmt(e)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
 type Print struct {
 	Expression expr.Expr
 }
@@ -39,6 +115,19 @@ func (e *Print) Accept(visitor StmtVisitor) (interface{}, error) {
 	return val, nil
 }
 
+type Return struct {
+	Keyword token.Token
+	Value   expr.Expr
+}
+
+func (e *Return) Accept(visitor StmtVisitor) (interface{}, error) {
+	val, err := visitor.VisitReturnStmt(e)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
 type Var struct {
 	Name        token.Token
 	Initializer expr.Expr
@@ -51,3 +140,16 @@ func (e *Var) Accept(visitor StmtVisitor) (interface{}, error) {
 	}
 	return val, nil
 }
+
+type While struct {
+	Condition expr.Expr
+	Body      Stmt
+}
+
+func (e *While) Accept(visitor StmtVisitor) (interface{}, error) {
+	val, err := visitor.VisitWhileStmt(e)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
@@ -10,7 +10,10 @@ const (
 	RIGHT_PAREN
 	LEFT_BRACE
 	RIGHT_BRACE
+	LEFT_BRACKET
+	RIGHT_BRACKET
 	COMMA
+	COLON
 	DOT
 	MINUS
 	PLUS
@@ -31,6 +34,10 @@ const (
 	// Literals.
 	IDENTIFIER
 	STRING
+	// INTERPOLATION is the part of a string literal up to a "${". It is
+	// followed by the tokens of the embedded expression and then either
+	// another INTERPOLATION or the STRING holding the rest of the literal.
+	INTERPOLATION
 	NUMBER
 
 	// Keywords.
@@ -41,6 +48,7 @@ const (
 	FUN
 	FOR
 	IF
+	IN
 	NIL
 	OR
 	PRINT
@@ -51,6 +59,9 @@ const (
 	VAR
 	WHILE
 
+	// COMMENT is only produced when the scanner is asked to preserve comments.
+	COMMENT
+
 	EOF
 )
 
@@ -59,6 +70,11 @@ type Token struct {
 	Lexeme  string
 	Literal interface{}
 	Line    int
+	// Column is the 1-based column of the token's first character on Line.
+	Column int
+	// Start and End are the byte offsets of the token in the source, End exclusive.
+	Start int
+	End   int
 }
 
 func NewToken(tokenType TokenType, lexeme string, literal interface{}, line int) Token {
@@ -0,0 +1,100 @@
+package vm
+
+import (
+	"github.com/joshbochu/golox/compiler"
+	"github.com/joshbochu/golox/loxerror"
+)
+
+// Iterator produces the values a for-in loop visits, returning false once
+// there are none left. It lives in a hidden local of the loop and is never
+// seen by Lox code.
+type Iterator struct {
+	next func() (compiler.Value, bool, *loxerror.RuntimeError)
+}
+
+func (i *Iterator) String() string {
+	return "<iterator>"
+}
+
+// iterate returns an iterator over value, following the tree walker. Lists
+// are walked by index, so elements pushed during the loop are visited too.
+// Maps yield the keys they held when the loop started and strings yield
+// their characters. An instance is iterable when its class has an iter()
+// method returning an iterator object, whose hasNext() method says whether
+// there is another value and whose next() method produces it.
+func (vm *VM) iterate(value compiler.Value) (*Iterator, *loxerror.RuntimeError) {
+	switch object := value.Object.(type) {
+	case *List:
+		n := 0
+		return &Iterator{func() (compiler.Value, bool, *loxerror.RuntimeError) {
+			if n >= len(object.Elements) {
+				return compiler.Nil, false, nil
+			}
+			n++
+			return object.Elements[n-1], true, nil
+		}}, nil
+	case *Map:
+		keys := append([]compiler.Value{}, object.Keys...)
+		return &Iterator{func() (compiler.Value, bool, *loxerror.RuntimeError) {
+			if len(keys) == 0 {
+				return compiler.Nil, false, nil
+			}
+			key := keys[0]
+			keys = keys[1:]
+			return key, true, nil
+		}}, nil
+	case string:
+		runes := []rune(object)
+		return &Iterator{func() (compiler.Value, bool, *loxerror.RuntimeError) {
+			if len(runes) == 0 {
+				return compiler.Nil, false, nil
+			}
+			r := runes[0]
+			runes = runes[1:]
+			return compiler.ObjectValue(string(r)), true, nil
+		}}, nil
+	case *Instance:
+		iter, ok := object.Class.Methods["iter"]
+		if !ok || iter.Function.Arity != 0 {
+			break
+		}
+		result, err := vm.callMethod(value, iter)
+		if err != nil {
+			return nil, err
+		}
+		iterator, ok := result.Object.(*Instance)
+		if !ok {
+			return nil, vm.runtimeError("iter() must return an instance with a next() method.")
+		}
+		hasNext, ok := iterator.Class.Methods["hasNext"]
+		next, nextOk := iterator.Class.Methods["next"]
+		if !ok || !nextOk || hasNext.Function.Arity != 0 || next.Function.Arity != 0 {
+			return nil, vm.runtimeError("iter() must return an instance with hasNext() and next() methods.")
+		}
+		return &Iterator{func() (compiler.Value, bool, *loxerror.RuntimeError) {
+			more, err := vm.callMethod(result, hasNext)
+			if err != nil || more.IsFalsey() {
+				return compiler.Nil, false, err
+			}
+			element, err := vm.callMethod(result, next)
+			if err != nil {
+				return compiler.Nil, false, err
+			}
+			return element, true, nil
+		}}, nil
+	}
+	return nil, vm.runtimeError("Can only iterate over lists, maps, strings, and instances with an iter() method.")
+}
+
+// callMethod calls a method taking no arguments on receiver and runs it to
+// completion, for the instructions that call back into Lox code.
+func (vm *VM) callMethod(receiver compiler.Value, method *Closure) (compiler.Value, *loxerror.RuntimeError) {
+	vm.push(receiver)
+	if err := vm.call(method, 0); err != nil {
+		return compiler.Nil, err
+	}
+	if err := vm.execute(len(vm.frames) - 1); err != nil {
+		return compiler.Nil, err
+	}
+	return vm.pop(), nil
+}
@@ -0,0 +1,84 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/joshbochu/golox/compiler"
+)
+
+// index checks that value is a valid index into the list, returning the
+// same errors as the tree walker.
+func (l *List) index(value compiler.Value) (int, error) {
+	number := value.Number
+	if value.Kind != compiler.KindNumber || number != math.Trunc(number) {
+		return 0, errors.New("List index must be an integer.")
+	}
+	if number < 0 {
+		return 0, errors.New("List index can't be negative.")
+	}
+	if number >= float64(len(l.Elements)) {
+		return 0, fmt.Errorf("List index %s is out of bounds for a list of length %d.", value, len(l.Elements))
+	}
+	return int(number), nil
+}
+
+func (l *List) get(index compiler.Value) (compiler.Value, error) {
+	n, err := l.index(index)
+	if err != nil {
+		return compiler.Nil, err
+	}
+	return l.Elements[n], nil
+}
+
+func (l *List) set(index compiler.Value, value compiler.Value) error {
+	n, err := l.index(index)
+	if err != nil {
+		return err
+	}
+	l.Elements[n] = value
+	return nil
+}
+
+// method returns the list's method called name, bound to the list.
+func (l *List) method(name string) (*Native, bool) {
+	switch name {
+	case "push":
+		return &Native{Name: name, Arity: 1, Fn: func(arguments []compiler.Value) (compiler.Value, error) {
+			l.Elements = append(l.Elements, arguments[0])
+			return compiler.Nil, nil
+		}}, true
+	case "pop":
+		return &Native{Name: name, Arity: 0, Fn: func(arguments []compiler.Value) (compiler.Value, error) {
+			if len(l.Elements) == 0 {
+				return compiler.Nil, errors.New("Can't pop from an empty list.")
+			}
+			last := l.Elements[len(l.Elements)-1]
+			l.Elements = l.Elements[:len(l.Elements)-1]
+			return last, nil
+		}}, true
+	case "len":
+		return &Native{Name: name, Arity: 0, Fn: func(arguments []compiler.Value) (compiler.Value, error) {
+			return compiler.NumberValue(float64(len(l.Elements))), nil
+		}}, true
+	case "slice":
+		return &Native{Name: name, Arity: 2, Fn: func(arguments []compiler.Value) (compiler.Value, error) {
+			start, err := integerArgument(name, arguments, 0)
+			if err != nil {
+				return compiler.Nil, err
+			}
+			end, err := integerArgument(name, arguments, 1)
+			if err != nil {
+				return compiler.Nil, err
+			}
+			if start < 0 || end < start || end > len(l.Elements) {
+				return compiler.Nil, fmt.Errorf("Slice [%d, %d) out of range for a list of length %d.", start, end, len(l.Elements))
+			}
+			// copy so the slice and the list don't share storage
+			elements := append([]compiler.Value{}, l.Elements[start:end]...)
+			return compiler.ObjectValue(&List{Elements: elements}), nil
+		}}, true
+	}
+	return nil, false
+}
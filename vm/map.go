@@ -0,0 +1,111 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/joshbochu/golox/compiler"
+)
+
+// checkKey reports whether key can be used as a map key, with the same
+// errors as the tree walker. Only values that compare by value can: nil,
+// booleans, numbers other than NaN, and strings.
+func checkKey(key compiler.Value) error {
+	switch key.Kind {
+	case compiler.KindNil, compiler.KindBool:
+		return nil
+	case compiler.KindNumber:
+		if math.IsNaN(key.Number) {
+			return errors.New("Can't use NaN as a map key.")
+		}
+		return nil
+	}
+	switch key.Object.(type) {
+	case string:
+		return nil
+	case *List:
+		return errors.New("Can't use a list as a map key.")
+	case *Map:
+		return errors.New("Can't use a map as a map key.")
+	case *Instance:
+		return errors.New("Can't use an instance as a map key.")
+	}
+	return fmt.Errorf("Can't use a %s as a map key.", typeOf(key))
+}
+
+func (m *Map) get(key compiler.Value) (compiler.Value, error) {
+	if err := checkKey(key); err != nil {
+		return compiler.Nil, err
+	}
+	value, ok := m.Values[key]
+	if !ok {
+		return compiler.Nil, fmt.Errorf("Undefined key '%s'.", key)
+	}
+	return value, nil
+}
+
+func (m *Map) set(key compiler.Value, value compiler.Value) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if _, ok := m.Values[key]; !ok {
+		m.Keys = append(m.Keys, key)
+	}
+	m.Values[key] = value
+	return nil
+}
+
+func (m *Map) remove(key compiler.Value) compiler.Value {
+	value, ok := m.Values[key]
+	if !ok {
+		return compiler.Nil
+	}
+	delete(m.Values, key)
+	for i, k := range m.Keys {
+		if k == key {
+			m.Keys = append(m.Keys[:i], m.Keys[i+1:]...)
+			break
+		}
+	}
+	return value
+}
+
+// method returns the map's method called name, bound to the map. Entries are
+// reached by indexing rather than as properties.
+func (m *Map) method(name string) (*Native, bool) {
+	switch name {
+	case "keys":
+		return &Native{Name: name, Arity: 0, Fn: func(arguments []compiler.Value) (compiler.Value, error) {
+			return compiler.ObjectValue(&List{Elements: append([]compiler.Value{}, m.Keys...)}), nil
+		}}, true
+	case "values":
+		return &Native{Name: name, Arity: 0, Fn: func(arguments []compiler.Value) (compiler.Value, error) {
+			values := make([]compiler.Value, len(m.Keys))
+			for i, key := range m.Keys {
+				values[i] = m.Values[key]
+			}
+			return compiler.ObjectValue(&List{Elements: values}), nil
+		}}, true
+	case "has":
+		return &Native{Name: name, Arity: 1, Fn: func(arguments []compiler.Value) (compiler.Value, error) {
+			if err := checkKey(arguments[0]); err != nil {
+				return compiler.Nil, err
+			}
+			_, ok := m.Values[arguments[0]]
+			return compiler.BoolValue(ok), nil
+		}}, true
+	case "remove":
+		return &Native{Name: name, Arity: 1, Fn: func(arguments []compiler.Value) (compiler.Value, error) {
+			if err := checkKey(arguments[0]); err != nil {
+				return compiler.Nil, err
+			}
+			return m.remove(arguments[0]), nil
+		}}, true
+	case "len":
+		return &Native{Name: name, Arity: 0, Fn: func(arguments []compiler.Value) (compiler.Value, error) {
+			return compiler.NumberValue(float64(len(m.Keys))), nil
+		}}, true
+	}
+	return nil, false
+}
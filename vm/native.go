@@ -0,0 +1,58 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/joshbochu/golox/compiler"
+)
+
+// NativeFn implements a native function. A non-nil error becomes a runtime
+// error at the call.
+type NativeFn func(arguments []compiler.Value) (compiler.Value, error)
+
+// Native is a function implemented in Go, such as the prelude's.
+type Native struct {
+	Name  string
+	Arity int
+	Fn    NativeFn
+}
+
+func (n *Native) String() string {
+	return "<native fn>"
+}
+
+// DefineNative defines a global native function taking arity arguments.
+func (vm *VM) DefineNative(name string, arity int, fn NativeFn) {
+	vm.globals[name] = compiler.ObjectValue(&Native{Name: name, Arity: arity, Fn: fn})
+}
+
+// The argument helpers below convert the nth argument of the native called
+// name, failing with the same messages as the tree walker's conversions.
+
+func numberArgument(name string, arguments []compiler.Value, n int) (float64, error) {
+	if arguments[n].Kind != compiler.KindNumber {
+		return 0, argumentError(name, n, "a number")
+	}
+	return arguments[n].Number, nil
+}
+
+func integerArgument(name string, arguments []compiler.Value, n int) (int, error) {
+	number := arguments[n].Number
+	if arguments[n].Kind != compiler.KindNumber || number != math.Trunc(number) || math.Abs(number) >= 1<<63 {
+		return 0, argumentError(name, n, "an integer")
+	}
+	return int(number), nil
+}
+
+func stringArgument(name string, arguments []compiler.Value, n int) (string, error) {
+	s, ok := arguments[n].AsString()
+	if !ok {
+		return "", argumentError(name, n, "a string")
+	}
+	return s, nil
+}
+
+func argumentError(name string, n int, expected string) error {
+	return fmt.Errorf("Argument %d to '%s' must be %s.", n+1, name, expected)
+}
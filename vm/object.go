@@ -0,0 +1,101 @@
+package vm
+
+import (
+	"strings"
+
+	"github.com/joshbochu/golox/compiler"
+)
+
+// Closure is a compiled function paired with the variables it captured.
+type Closure struct {
+	Function *compiler.Function
+	Upvalues []*Upvalue
+}
+
+func (c *Closure) String() string {
+	return c.Function.String()
+}
+
+// Upvalue is a variable captured by a closure. While the variable is still
+// on the stack the upvalue is open and refers to its slot; once the slot is
+// popped the value moves into the upvalue itself.
+type Upvalue struct {
+	slot   int
+	closed compiler.Value
+	open   bool
+	// next links the open upvalues, sorted by slot from the top of the stack.
+	next *Upvalue
+}
+
+type Class struct {
+	Name    string
+	Methods map[string]*Closure
+}
+
+func (c *Class) String() string {
+	return c.Name
+}
+
+type Instance struct {
+	Class  *Class
+	Fields map[string]compiler.Value
+}
+
+func NewInstance(class *Class) *Instance {
+	return &Instance{Class: class, Fields: make(map[string]compiler.Value)}
+}
+
+func (i *Instance) String() string {
+	return i.Class.Name + " instance"
+}
+
+// BoundMethod is a method looked up on an instance, remembering the
+// instance to use as "this".
+type BoundMethod struct {
+	Receiver compiler.Value
+	Method   *Closure
+}
+
+func (b *BoundMethod) String() string {
+	return b.Method.String()
+}
+
+// collection is implemented by lists and maps, whose elements are reached by
+// indexing and whose methods are natives bound to the collection.
+type collection interface {
+	get(index compiler.Value) (compiler.Value, error)
+	set(index compiler.Value, value compiler.Value) error
+	method(name string) (*Native, bool)
+}
+
+// List is an ordered, growable sequence of values.
+type List struct {
+	Elements []compiler.Value
+}
+
+func (l *List) String() string {
+	parts := make([]string, len(l.Elements))
+	for i, element := range l.Elements {
+		parts[i] = element.String()
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// Map maps keys to values, remembering the order keys were first added in.
+// Keys are checked with checkKey, so they compare by value.
+type Map struct {
+	Keys   []compiler.Value
+	Values map[compiler.Value]compiler.Value
+}
+
+func NewMap() *Map {
+	return &Map{Values: make(map[compiler.Value]compiler.Value)}
+}
+
+func (m *Map) String() string {
+	parts := make([]string, len(m.Keys))
+	for i, key := range m.Keys {
+		parts[i] = key.String() + ": " + m.Values[key].String()
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
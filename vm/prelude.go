@@ -0,0 +1,196 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/joshbochu/golox/compiler"
+)
+
+// maxFractionDigits bounds formatNumber's digits argument.
+const maxFractionDigits = 100
+
+// definePrelude defines the same built-in functions as the tree walker,
+// which its testdata/prelude golden files check.
+func (vm *VM) definePrelude() {
+	vm.DefineNative("clock", 0, func(arguments []compiler.Value) (compiler.Value, error) {
+		return compiler.NumberValue(float64(time.Now().UnixNano()) / float64(time.Second)), nil
+	})
+
+	vm.defineMath("sqrt", math.Sqrt)
+	vm.defineMath("floor", math.Floor)
+	vm.DefineNative("pow", 2, func(arguments []compiler.Value) (compiler.Value, error) {
+		x, err := numberArgument("pow", arguments, 0)
+		if err != nil {
+			return compiler.Nil, err
+		}
+		y, err := numberArgument("pow", arguments, 1)
+		if err != nil {
+			return compiler.Nil, err
+		}
+		return compiler.NumberValue(math.Pow(x, y)), nil
+	})
+	vm.DefineNative("random", 0, func(arguments []compiler.Value) (compiler.Value, error) {
+		return compiler.NumberValue(vm.random.Float64()), nil
+	})
+	vm.DefineNative("seed", 1, func(arguments []compiler.Value) (compiler.Value, error) {
+		seed, err := integerArgument("seed", arguments, 0)
+		if err != nil {
+			return compiler.Nil, err
+		}
+		vm.random.Seed(int64(seed))
+		return compiler.Nil, nil
+	})
+
+	vm.DefineNative("len", 1, func(arguments []compiler.Value) (compiler.Value, error) {
+		if s, ok := arguments[0].AsString(); ok {
+			return compiler.NumberValue(float64(utf8.RuneCountInString(s))), nil
+		}
+		switch object := arguments[0].Object.(type) {
+		case *List:
+			return compiler.NumberValue(float64(len(object.Elements))), nil
+		case *Map:
+			return compiler.NumberValue(float64(len(object.Keys))), nil
+		}
+		return compiler.Nil, errors.New("Argument 1 to 'len' must be a string, a list, or a map.")
+	})
+	vm.DefineNative("substr", 3, func(arguments []compiler.Value) (compiler.Value, error) {
+		s, err := stringArgument("substr", arguments, 0)
+		if err != nil {
+			return compiler.Nil, err
+		}
+		start, err := integerArgument("substr", arguments, 1)
+		if err != nil {
+			return compiler.Nil, err
+		}
+		length, err := integerArgument("substr", arguments, 2)
+		if err != nil {
+			return compiler.Nil, err
+		}
+		runes := []rune(s)
+		if start < 0 || length < 0 || start+length > len(runes) {
+			return compiler.Nil, fmt.Errorf("Substring [%d, %d) out of range for a string of length %d.", start, start+length, len(runes))
+		}
+		return compiler.ObjectValue(string(runes[start : start+length])), nil
+	})
+	vm.DefineNative("indexOf", 2, func(arguments []compiler.Value) (compiler.Value, error) {
+		s, err := stringArgument("indexOf", arguments, 0)
+		if err != nil {
+			return compiler.Nil, err
+		}
+		substring, err := stringArgument("indexOf", arguments, 1)
+		if err != nil {
+			return compiler.Nil, err
+		}
+		index := strings.Index(s, substring)
+		if index != -1 {
+			index = utf8.RuneCountInString(s[:index])
+		}
+		return compiler.NumberValue(float64(index)), nil
+	})
+	vm.defineString("upper", strings.ToUpper)
+	vm.defineString("lower", strings.ToLower)
+	vm.DefineNative("split", 2, func(arguments []compiler.Value) (compiler.Value, error) {
+		s, err := stringArgument("split", arguments, 0)
+		if err != nil {
+			return compiler.Nil, err
+		}
+		separator, err := stringArgument("split", arguments, 1)
+		if err != nil {
+			return compiler.Nil, err
+		}
+		parts := strings.Split(s, separator)
+		elements := make([]compiler.Value, len(parts))
+		for n, part := range parts {
+			elements[n] = compiler.ObjectValue(part)
+		}
+		return compiler.ObjectValue(&List{Elements: elements}), nil
+	})
+
+	vm.DefineNative("parseNumber", 1, func(arguments []compiler.Value) (compiler.Value, error) {
+		s, err := stringArgument("parseNumber", arguments, 0)
+		if err != nil {
+			return compiler.Nil, err
+		}
+		number, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return compiler.Nil, nil
+		}
+		return compiler.NumberValue(number), nil
+	})
+	vm.DefineNative("toString", 1, func(arguments []compiler.Value) (compiler.Value, error) {
+		return compiler.ObjectValue(arguments[0].String()), nil
+	})
+	vm.DefineNative("formatNumber", 2, func(arguments []compiler.Value) (compiler.Value, error) {
+		number, err := numberArgument("formatNumber", arguments, 0)
+		if err != nil {
+			return compiler.Nil, err
+		}
+		digits, err := integerArgument("formatNumber", arguments, 1)
+		if err != nil {
+			return compiler.Nil, err
+		}
+		if digits < 0 || digits > maxFractionDigits {
+			return compiler.Nil, fmt.Errorf("Argument 2 to 'formatNumber' must be between 0 and %d.", maxFractionDigits)
+		}
+		return compiler.ObjectValue(strconv.FormatFloat(number, 'f', digits, 64)), nil
+	})
+
+	vm.DefineNative("typeof", 1, func(arguments []compiler.Value) (compiler.Value, error) {
+		return compiler.ObjectValue(typeOf(arguments[0])), nil
+	})
+}
+
+// defineMath defines a native taking and returning one number.
+func (vm *VM) defineMath(name string, fn func(float64) float64) {
+	vm.DefineNative(name, 1, func(arguments []compiler.Value) (compiler.Value, error) {
+		x, err := numberArgument(name, arguments, 0)
+		if err != nil {
+			return compiler.Nil, err
+		}
+		return compiler.NumberValue(fn(x)), nil
+	})
+}
+
+// defineString defines a native taking and returning one string.
+func (vm *VM) defineString(name string, fn func(string) string) {
+	vm.DefineNative(name, 1, func(arguments []compiler.Value) (compiler.Value, error) {
+		s, err := stringArgument(name, arguments, 0)
+		if err != nil {
+			return compiler.Nil, err
+		}
+		return compiler.ObjectValue(fn(s)), nil
+	})
+}
+
+// typeOf names the type of a value as the tree walker's typeof does.
+func typeOf(value compiler.Value) string {
+	switch value.Kind {
+	case compiler.KindNil:
+		return "nil"
+	case compiler.KindBool:
+		return "boolean"
+	case compiler.KindNumber:
+		return "number"
+	}
+	switch value.Object.(type) {
+	case string:
+		return "string"
+	case *Class:
+		return "class"
+	case *Closure, *BoundMethod, *Native:
+		return "function"
+	case *Instance:
+		return "instance"
+	case *List:
+		return "list"
+	case *Map:
+		return "map"
+	}
+	return "unknown"
+}
@@ -0,0 +1,45 @@
+package vm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joshbochu/golox/loxerror"
+)
+
+// TestPreludeMatchesInterpreter runs the tree walker's prelude scripts and
+// expects exactly the output and errors recorded in its golden files.
+func TestPreludeMatchesInterpreter(t *testing.T) {
+	scripts, err := filepath.Glob(filepath.Join("..", "interpreter", "testdata", "prelude", "*.lox"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) == 0 {
+		t.Fatal("No scripts found")
+	}
+
+	for _, script := range scripts {
+		name := strings.TrimSuffix(filepath.Base(script), ".lox")
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reporter := &loxerror.Collector{}
+			output := run(t, NewVM(reporter), string(source))
+			for _, d := range reporter.Diagnostics {
+				output += d.String() + "\n"
+			}
+
+			expected, err := os.ReadFile(strings.TrimSuffix(script, ".lox") + ".golden")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if output != string(expected) {
+				t.Errorf("Expected:\n%s\ngot:\n%s", expected, output)
+			}
+		})
+	}
+}
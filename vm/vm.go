@@ -0,0 +1,482 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/joshbochu/golox/compiler"
+	"github.com/joshbochu/golox/loxerror"
+	"github.com/joshbochu/golox/token"
+)
+
+// maxFrames bounds the call depth, so runaway recursion is reported rather
+// than exhausting memory.
+const maxFrames = 1 << 16
+
+type callFrame struct {
+	closure *Closure
+	ip      int
+	// base is the stack slot holding the callee, the frame's slot zero.
+	base int
+}
+
+// VM executes compiled functions. Globals persist across calls to Interpret.
+type VM struct {
+	frames  []callFrame
+	stack   []compiler.Value
+	globals map[string]compiler.Value
+	// openUpvalues lists the upvalues still pointing into the stack, highest
+	// slot first.
+	openUpvalues *Upvalue
+	out          io.Writer
+	random       *rand.Rand
+	reporter     loxerror.Reporter
+}
+
+func NewVM(reporter loxerror.Reporter) *VM {
+	vm := &VM{
+		globals:  make(map[string]compiler.Value),
+		out:      os.Stdout,
+		random:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		reporter: reporter,
+	}
+	vm.definePrelude()
+	return vm
+}
+
+// Interpret runs a compiled script, stopping at and reporting the first
+// runtime error.
+func (vm *VM) Interpret(function *compiler.Function) {
+	if err := vm.run(function); err != nil {
+		vm.reporter.Report(err.Diagnostic())
+	}
+}
+
+func (vm *VM) run(function *compiler.Function) *loxerror.RuntimeError {
+	// start clean in case the last script stopped at an error
+	vm.stack = vm.stack[:0]
+	vm.frames = vm.frames[:0]
+	vm.openUpvalues = nil
+
+	closure := &Closure{Function: function}
+	vm.push(compiler.ObjectValue(closure))
+	if err := vm.call(closure, 0); err != nil {
+		return err
+	}
+	return vm.execute(0)
+}
+
+// execute runs the innermost frame until the number of frames drops back to
+// depth, leaving the value the last frame returned on the stack.
+func (vm *VM) execute(depth int) *loxerror.RuntimeError {
+	frame := &vm.frames[len(vm.frames)-1]
+	chunk := frame.closure.Function.Chunk
+
+	readByte := func() byte {
+		b := chunk.Code[frame.ip]
+		frame.ip++
+		return b
+	}
+	readShort := func() int {
+		n := chunk.ReadShort(frame.ip)
+		frame.ip += 2
+		return n
+	}
+	readConstant := func() compiler.Value {
+		return chunk.Constants[readShort()]
+	}
+	readString := func() string {
+		return readConstant().Object.(string)
+	}
+
+	for {
+		switch op := compiler.OpCode(readByte()); op {
+		case compiler.OpConstant:
+			vm.push(readConstant())
+		case compiler.OpNil:
+			vm.push(compiler.Nil)
+		case compiler.OpTrue:
+			vm.push(compiler.BoolValue(true))
+		case compiler.OpFalse:
+			vm.push(compiler.BoolValue(false))
+		case compiler.OpPop:
+			vm.pop()
+		case compiler.OpGetLocal:
+			vm.push(vm.stack[frame.base+int(readByte())])
+		case compiler.OpSetLocal:
+			vm.stack[frame.base+int(readByte())] = vm.peek(0)
+		case compiler.OpGetGlobal:
+			name := readString()
+			value, ok := vm.globals[name]
+			if !ok {
+				return vm.runtimeError("Undefined variable '%s'.", name)
+			}
+			vm.push(value)
+		case compiler.OpDefineGlobal:
+			vm.globals[readString()] = vm.pop()
+		case compiler.OpSetGlobal:
+			name := readString()
+			if _, ok := vm.globals[name]; !ok {
+				return vm.runtimeError("Undefined variable '%s'.", name)
+			}
+			vm.globals[name] = vm.peek(0)
+		case compiler.OpGetUpvalue:
+			vm.push(vm.upvalueValue(frame.closure.Upvalues[readByte()]))
+		case compiler.OpSetUpvalue:
+			vm.setUpvalue(frame.closure.Upvalues[readByte()], vm.peek(0))
+		case compiler.OpGetProperty:
+			name := readString()
+			if object, ok := vm.peek(0).Object.(collection); ok {
+				method, ok := object.method(name)
+				if !ok {
+					return vm.runtimeError("Undefined property '%s'.", name)
+				}
+				vm.pop()
+				vm.push(compiler.ObjectValue(method))
+				break
+			}
+			instance, ok := vm.peek(0).Object.(*Instance)
+			if !ok {
+				return vm.runtimeError("Only instances have properties.")
+			}
+			if value, ok := instance.Fields[name]; ok {
+				vm.pop()
+				vm.push(value)
+			} else if !vm.bindMethod(instance.Class, name) {
+				return vm.runtimeError("Undefined property '%s'.", name)
+			}
+		case compiler.OpSetProperty:
+			instance, ok := vm.peek(1).Object.(*Instance)
+			if !ok {
+				return vm.runtimeError("Only instances have fields.")
+			}
+			instance.Fields[readString()] = vm.peek(0)
+			value := vm.pop()
+			vm.pop()
+			vm.push(value)
+		case compiler.OpGetSuper:
+			name := readString()
+			superclass := vm.pop().Object.(*Class)
+			if !vm.bindMethod(superclass, name) {
+				return vm.runtimeError("Undefined property '%s'.", name)
+			}
+		case compiler.OpBuildList:
+			count := readShort()
+			elements := make([]compiler.Value, count)
+			copy(elements, vm.stack[len(vm.stack)-count:])
+			vm.stack = vm.stack[:len(vm.stack)-count]
+			vm.push(compiler.ObjectValue(&List{Elements: elements}))
+		case compiler.OpBuildMap:
+			count := readShort()
+			entries := vm.stack[len(vm.stack)-2*count:]
+			m := NewMap()
+			for n := 0; n < len(entries); n += 2 {
+				if err := m.set(entries[n], entries[n+1]); err != nil {
+					return vm.runtimeError("%s", err.Error())
+				}
+			}
+			vm.stack = vm.stack[:len(vm.stack)-2*count]
+			vm.push(compiler.ObjectValue(m))
+		case compiler.OpGetIndex:
+			object, ok := vm.peek(1).Object.(collection)
+			if !ok {
+				return vm.runtimeError("Only lists and maps can be indexed.")
+			}
+			value, err := object.get(vm.peek(0))
+			if err != nil {
+				return vm.runtimeError("%s", err.Error())
+			}
+			vm.pop()
+			vm.pop()
+			vm.push(value)
+		case compiler.OpSetIndex:
+			object, ok := vm.peek(2).Object.(collection)
+			if !ok {
+				return vm.runtimeError("Only lists and maps can be indexed.")
+			}
+			if err := object.set(vm.peek(1), vm.peek(0)); err != nil {
+				return vm.runtimeError("%s", err.Error())
+			}
+			value := vm.pop()
+			vm.pop()
+			vm.pop()
+			vm.push(value)
+		case compiler.OpIterate:
+			iterator, err := vm.iterate(vm.peek(0))
+			if err != nil {
+				return err
+			}
+			// calling iter() may have grown the frame stack
+			frame = &vm.frames[len(vm.frames)-1]
+			vm.pop()
+			vm.push(compiler.ObjectValue(iterator))
+		case compiler.OpForIter:
+			offset := readShort()
+			value, ok, err := vm.peek(0).Object.(*Iterator).next()
+			if err != nil {
+				return err
+			}
+			frame = &vm.frames[len(vm.frames)-1]
+			if ok {
+				vm.push(value)
+			} else {
+				frame.ip += offset
+			}
+		case compiler.OpEqual:
+			b := vm.pop()
+			a := vm.pop()
+			vm.push(compiler.BoolValue(a.Equal(b)))
+		case compiler.OpGreater, compiler.OpGreaterEqual, compiler.OpLess, compiler.OpLessEqual,
+			compiler.OpSubtract, compiler.OpMultiply, compiler.OpDivide:
+			if err := vm.binaryOp(op); err != nil {
+				return err
+			}
+		case compiler.OpAdd:
+			b, a := vm.peek(0), vm.peek(1)
+			if a.Kind == compiler.KindNumber && b.Kind == compiler.KindNumber {
+				vm.pop()
+				vm.pop()
+				vm.push(compiler.NumberValue(a.Number + b.Number))
+				break
+			}
+			left, leftOk := a.AsString()
+			right, rightOk := b.AsString()
+			if !leftOk || !rightOk {
+				return vm.runtimeError("operands must be two numbers or two strings for + operator.")
+			}
+			vm.pop()
+			vm.pop()
+			vm.push(compiler.ObjectValue(left + right))
+		case compiler.OpNot:
+			vm.push(compiler.BoolValue(vm.pop().IsFalsey()))
+		case compiler.OpNegate:
+			if vm.peek(0).Kind != compiler.KindNumber {
+				return vm.runtimeError("operand must be a number")
+			}
+			vm.push(compiler.NumberValue(-vm.pop().Number))
+		case compiler.OpStringify:
+			vm.push(compiler.ObjectValue(vm.pop().String()))
+		case compiler.OpPrint:
+			fmt.Fprintln(vm.out, vm.pop().String())
+		case compiler.OpJump:
+			offset := readShort()
+			frame.ip += offset
+		case compiler.OpJumpIfFalse:
+			offset := readShort()
+			if vm.peek(0).IsFalsey() {
+				frame.ip += offset
+			}
+		case compiler.OpLoop:
+			offset := readShort()
+			frame.ip -= offset
+		case compiler.OpCall:
+			argCount := int(readByte())
+			if err := vm.callValue(vm.peek(argCount), argCount); err != nil {
+				return err
+			}
+			frame = &vm.frames[len(vm.frames)-1]
+			chunk = frame.closure.Function.Chunk
+		case compiler.OpClosure:
+			function := readConstant().Object.(*compiler.Function)
+			closure := &Closure{Function: function, Upvalues: make([]*Upvalue, function.UpvalueCount)}
+			for i := range closure.Upvalues {
+				isLocal := readByte() == 1
+				index := int(readByte())
+				if isLocal {
+					closure.Upvalues[i] = vm.captureUpvalue(frame.base + index)
+				} else {
+					closure.Upvalues[i] = frame.closure.Upvalues[index]
+				}
+			}
+			vm.push(compiler.ObjectValue(closure))
+		case compiler.OpCloseUpvalue:
+			vm.closeUpvalues(len(vm.stack) - 1)
+			vm.pop()
+		case compiler.OpReturn:
+			result := vm.pop()
+			vm.closeUpvalues(frame.base)
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			vm.stack = vm.stack[:frame.base]
+			vm.push(result)
+			if len(vm.frames) == depth {
+				return nil
+			}
+			frame = &vm.frames[len(vm.frames)-1]
+			chunk = frame.closure.Function.Chunk
+		case compiler.OpClass:
+			vm.push(compiler.ObjectValue(&Class{Name: readString(), Methods: make(map[string]*Closure)}))
+		case compiler.OpInherit:
+			superclass, ok := vm.peek(1).Object.(*Class)
+			if !ok {
+				return vm.runtimeError("Superclass must be a class.")
+			}
+			subclass := vm.peek(0).Object.(*Class)
+			// copied down now, since classes can't change once declared
+			for name, method := range superclass.Methods {
+				subclass.Methods[name] = method
+			}
+			vm.pop()
+		case compiler.OpMethod:
+			method := vm.peek(0).Object.(*Closure)
+			class := vm.peek(1).Object.(*Class)
+			class.Methods[readString()] = method
+			vm.pop()
+		default:
+			return vm.runtimeError("Unknown opcode %d.", op)
+		}
+	}
+}
+
+// binaryOp applies an arithmetic or comparison operator to two numbers.
+func (vm *VM) binaryOp(op compiler.OpCode) *loxerror.RuntimeError {
+	b, a := vm.peek(0), vm.peek(1)
+	if a.Kind != compiler.KindNumber || b.Kind != compiler.KindNumber {
+		return vm.runtimeError("operands must be numbers")
+	}
+	vm.pop()
+	vm.pop()
+	left, right := a.Number, b.Number
+	switch op {
+	case compiler.OpGreater:
+		vm.push(compiler.BoolValue(left > right))
+	case compiler.OpGreaterEqual:
+		vm.push(compiler.BoolValue(left >= right))
+	case compiler.OpLess:
+		vm.push(compiler.BoolValue(left < right))
+	case compiler.OpLessEqual:
+		vm.push(compiler.BoolValue(left <= right))
+	case compiler.OpSubtract:
+		vm.push(compiler.NumberValue(left - right))
+	case compiler.OpMultiply:
+		vm.push(compiler.NumberValue(left * right))
+	case compiler.OpDivide:
+		vm.push(compiler.NumberValue(left / right))
+	}
+	return nil
+}
+
+func (vm *VM) callValue(callee compiler.Value, argCount int) *loxerror.RuntimeError {
+	switch callee := callee.Object.(type) {
+	case *Closure:
+		return vm.call(callee, argCount)
+	case *BoundMethod:
+		vm.stack[len(vm.stack)-argCount-1] = callee.Receiver
+		return vm.call(callee.Method, argCount)
+	case *Class:
+		vm.stack[len(vm.stack)-argCount-1] = compiler.ObjectValue(NewInstance(callee))
+		if initializer, ok := callee.Methods["init"]; ok {
+			return vm.call(initializer, argCount)
+		}
+		if argCount != 0 {
+			return vm.runtimeError("Expected 0 arguments but got %d.", argCount)
+		}
+		return nil
+	case *Native:
+		if argCount != callee.Arity {
+			return vm.runtimeError("Expected %d arguments but got %d.", callee.Arity, argCount)
+		}
+		result, err := callee.Fn(vm.stack[len(vm.stack)-argCount:])
+		if err != nil {
+			return vm.runtimeError("%s", err.Error())
+		}
+		vm.stack = vm.stack[:len(vm.stack)-argCount-1]
+		vm.push(result)
+		return nil
+	}
+	return vm.runtimeError("Can only call functions and classes.")
+}
+
+func (vm *VM) call(closure *Closure, argCount int) *loxerror.RuntimeError {
+	if argCount != closure.Function.Arity {
+		return vm.runtimeError("Expected %d arguments but got %d.", closure.Function.Arity, argCount)
+	}
+	if len(vm.frames) == maxFrames {
+		return vm.runtimeError("Stack overflow.")
+	}
+	vm.frames = append(vm.frames, callFrame{closure: closure, base: len(vm.stack) - argCount - 1})
+	return nil
+}
+
+// bindMethod replaces the instance on top of the stack with its method
+// called name, reporting whether class has one.
+func (vm *VM) bindMethod(class *Class, name string) bool {
+	method, ok := class.Methods[name]
+	if !ok {
+		return false
+	}
+	bound := &BoundMethod{Receiver: vm.pop(), Method: method}
+	vm.push(compiler.ObjectValue(bound))
+	return true
+}
+
+// captureUpvalue returns the open upvalue for slot, creating it if no
+// closure has captured that variable yet.
+func (vm *VM) captureUpvalue(slot int) *Upvalue {
+	var previous *Upvalue
+	upvalue := vm.openUpvalues
+	for upvalue != nil && upvalue.slot > slot {
+		previous = upvalue
+		upvalue = upvalue.next
+	}
+	if upvalue != nil && upvalue.slot == slot {
+		return upvalue
+	}
+
+	created := &Upvalue{slot: slot, open: true, next: upvalue}
+	if previous == nil {
+		vm.openUpvalues = created
+	} else {
+		previous.next = created
+	}
+	return created
+}
+
+// closeUpvalues closes every open upvalue at or above slot last.
+func (vm *VM) closeUpvalues(last int) {
+	for vm.openUpvalues != nil && vm.openUpvalues.slot >= last {
+		upvalue := vm.openUpvalues
+		upvalue.closed = vm.stack[upvalue.slot]
+		upvalue.open = false
+		vm.openUpvalues = upvalue.next
+	}
+}
+
+func (vm *VM) upvalueValue(upvalue *Upvalue) compiler.Value {
+	if upvalue.open {
+		return vm.stack[upvalue.slot]
+	}
+	return upvalue.closed
+}
+
+func (vm *VM) setUpvalue(upvalue *Upvalue, value compiler.Value) {
+	if upvalue.open {
+		vm.stack[upvalue.slot] = value
+	} else {
+		upvalue.closed = value
+	}
+}
+
+func (vm *VM) push(value compiler.Value) {
+	vm.stack = append(vm.stack, value)
+}
+
+func (vm *VM) pop() compiler.Value {
+	value := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return value
+}
+
+func (vm *VM) peek(distance int) compiler.Value {
+	return vm.stack[len(vm.stack)-1-distance]
+}
+
+// runtimeError builds an error located at the line of the instruction being
+// executed in the innermost frame.
+func (vm *VM) runtimeError(format string, args ...interface{}) *loxerror.RuntimeError {
+	frame := vm.frames[len(vm.frames)-1]
+	line := frame.closure.Function.Chunk.Line(frame.ip - 1)
+	return loxerror.NewRuntimeError(token.Token{Line: line}, fmt.Sprintf(format, args...))
+}
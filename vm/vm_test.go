@@ -0,0 +1,258 @@
+package vm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/joshbochu/golox/compiler"
+	"github.com/joshbochu/golox/expr"
+	"github.com/joshbochu/golox/loxerror"
+	"github.com/joshbochu/golox/parser"
+	"github.com/joshbochu/golox/resolver"
+	"github.com/joshbochu/golox/scanner"
+)
+
+// noLocals discards the resolver's results, which the compiler works out itself.
+type noLocals struct{}
+
+func (noLocals) Resolve(expr expr.Expr, depth int) {}
+
+// run compiles and interprets source on vm, returning what it printed.
+func run(t *testing.T, vm *VM, source string) string {
+	t.Helper()
+	reporter := &loxerror.Collector{}
+	tokens := scanner.NewScanner(source, reporter).ScanTokens()
+	statements, err := parser.NewParser(tokens, reporter).Parse()
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if err := resolver.NewResolver(noLocals{}, reporter).Resolve(statements); err != nil {
+		t.Fatalf("Resolve error: %v", err)
+	}
+	function, err := compiler.NewCompiler(reporter).Compile(statements)
+	if err != nil {
+		t.Fatalf("Compile error: %v", err)
+	}
+	// run what a compiled file would hold, so every test also checks that
+	// Decode accepts the compiler's output
+	var compiled bytes.Buffer
+	if err := compiler.Encode(&compiled, function); err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	if function, err = compiler.Decode(&compiled); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	var out strings.Builder
+	vm.out = &out
+	vm.Interpret(function)
+	return out.String()
+}
+
+func TestVM(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Arithmetic", "print 4 + (5 * 3) - 2 / 4;", "18.5\n"},
+		{"Comparison", "print 1 < 2; print 2 <= 1; print 3 > 3; print 3 >= 3;", "true\nfalse\nfalse\ntrue\n"},
+		{"Equality", `print nil == nil; print 1 == "1"; print "a" + "b" == "ab"; print true != false;`, "true\nfalse\ntrue\ntrue\n"},
+		{"Not and negate", "print !nil; print !0; print -(1 + 2);", "true\nfalse\n-3\n"},
+		{"Numbers print like the interpreter", "print 1000000; print 0.5; print 10 / 4;", "1e+06\n0.5\n2.5\n"},
+		{"Logical", `print nil or "default"; print 1 and 2; print false and undefined;`, "default\n2\nfalse\n"},
+		{"Interpolation", `var n = 2; print "${n} + ${n} = ${n + n}, ${nil}";`, "2 + 2 = 4, nil\n"},
+		{"Globals", "var a = 1; a = a + 1; print a;", "2\n"},
+		{"Block scope", `var a = "global"; { var a = "outer"; { var a = "inner"; print a; } print a; } print a;`, "inner\nouter\nglobal\n"},
+		{"If else", `if (1 > 2) print "then"; else print "else"; if (nil) print "no";`, "else\n"},
+		{"While", "var i = 0; while (i < 3) { print i; i = i + 1; }", "0\n1\n2\n"},
+		{"For", "for (var i = 0; i < 3; i = i + 1) print i * i;", "0\n1\n4\n"},
+		{"Recursion", "fun fib(n) { if (n < 2) return n; return fib(n - 1) + fib(n - 2); } print fib(15);", "610\n"},
+		{"Implicit return", "fun f() {} print f();", "nil\n"},
+		{"Function values", "fun f() {} print f; print f == f;", "<fn f>\ntrue\n"},
+		{
+			"Closures",
+			`fun counter() { var n = 0; fun inc() { n = n + 1; return n; } return inc; }
+			var a = counter(); var b = counter(); a(); print a(); print b();`,
+			"2\n1\n",
+		},
+		{
+			"Closures see later assignments",
+			`var f; { var x = "before"; fun g() { print x; } x = "after"; f = g; } f();`,
+			"after\n",
+		},
+		{
+			"Nested upvalues",
+			`fun a() { var x = "x"; fun b() { fun c() { return x; } return c; } return b; } print a()()();`,
+			"x\n",
+		},
+		{
+			"Classes",
+			`class Point { init(x, y) { this.x = x; this.y = y; } sum() { return this.x + this.y; } }
+			var p = Point(1, 2); print p.sum(); p.x = 10; print p.sum(); print p; print Point;`,
+			"3\n12\nPoint instance\nPoint\n",
+		},
+		{
+			"Bound methods keep their receiver",
+			`class A { init(n) { this.n = n; } get() { return this.n; } } var m = A("a").get; print m(); print m;`,
+			"a\n<fn get>\n",
+		},
+		{
+			"Fields shadow methods",
+			`class A { m() { return "method"; } } var a = A(); a.m = "field"; print a.m;`,
+			"field\n",
+		},
+		{
+			"Initializer returns this",
+			`class A { init() { this.x = 1; return; } } var a = A(); print a.init() == a;`,
+			"true\n",
+		},
+		{
+			"Inheritance and super",
+			`class A { hi() { return "A"; } name() { return "a"; } }
+			class B < A { hi() { return "B" + super.hi(); } }
+			class C < B { hi() { return "C" + super.hi(); } }
+			print C().hi(); print C().name();`,
+			"CBA\na\n",
+		},
+		{"Clock", "var t = clock(); print t > 0; print clock() >= t;", "true\ntrue\n"},
+		{"Len", `print len("héllo"); print len(split("a,b", ","));`, "5\n2\n"},
+		{"Typeof", `class A { m() {} } print typeof(A().m); print typeof(A); print typeof(len); print typeof("s");`, "function\nclass\nfunction\nstring\n"},
+		{"Natives are values", "print clock; var c = clock; print c == clock;", "<native fn>\ntrue\n"},
+		{"Natives can be shadowed", "fun len(x) { return -1; } print len(\"abc\");", "-1\n"},
+		{"List literals", `var a = [1, "two", [nil]]; print a; print []; print typeof(a);`, "[1, two, [nil]]\n[]\nlist\n"},
+		{"List indexing", "var a = [1, 2, 3]; print a[0] + a[2]; a[1] = a[1] * 10; print a; print a[0] = 5;", "4\n[1, 20, 3]\n5\n"},
+		{
+			"List methods",
+			"var a = [1]; a.push(2); print a.len(); print a.pop(); print a; var s = [1, 2, 3].slice(1, 3); print s; var push = a.push; push(4); print a;",
+			"2\n2\n[1]\n[2, 3]\n[1, 4]\n",
+		},
+		{"Lists compare by identity", "var a = [1]; print a == a; print [1] == [1];", "true\nfalse\n"},
+		{"Map literals", `var m = {"a": 1, 2: [true], nil: {}}; print m; print {}; print typeof(m); print len(m);`, "{a: 1, 2: [true], nil: {}}\n{}\nmap\n3\n"},
+		{"Map indexing", `var m = {"a": 1}; m["b"] = 2; m["a"] = m["a"] + m["b"]; print m; print m[-0] = "zero"; print m[0];`, "{a: 3, b: 2}\nzero\nzero\n"},
+		{
+			"Map methods",
+			`var m = {"a": 1, "b": 2, "c": 3}; print m.remove("b"); print m.remove("x"); print m.has("a"); print m.has("b"); print m.keys(); print m.values(); print m.len();`,
+			"2\nnil\ntrue\nfalse\n[a, c]\n[1, 3]\n2\n",
+		},
+		{"For-in over a list", "for (var x in [1, nil, 3]) print x;", "1\nnil\n3\n"},
+		{"For-in visits pushed elements", "var a = [1]; for (var x in a) if (x < 3) a.push(x + 1); print a;", "[1, 2, 3]\n"},
+		{"For-in over map keys", `var m = {"b": 1, "a": 2}; for (var k in m) { m.remove(k); print k; } print m;`, "b\na\n{}\n"},
+		{"For-in over a string", `for (var c in "añb") print c;`, "a\nñ\nb\n"},
+		{
+			"For-in iterator protocol",
+			`class Countdown { init(n) { this.n = n; } iter() { return CountdownIter(this.n); } }
+			class CountdownIter {
+				init(n) { this.n = n; }
+				hasNext() { return this.n > 0; }
+				next() { this.n = this.n - 1; return this.n + 1; }
+			}
+			for (var n in Countdown(3)) { for (var m in Countdown(n - 1)) print n * 10 + m; }`,
+			"32\n31\n21\n",
+		},
+		{
+			"For-in iterators can yield nil",
+			`class Each {
+				init(list) { this.list = list; this.n = 0; }
+				iter() { return this; }
+				hasNext() { return this.n < this.list.len(); }
+				next() { this.n = this.n + 1; return this.list[this.n - 1]; }
+			}
+			for (var x in Each([1, nil, 2])) print x;`,
+			"1\nnil\n2\n",
+		},
+		{
+			"For-in has a variable per iteration",
+			"var fs = []; for (var x in [1, 2]) { fun f() { return x; } fs.push(f); } print fs[0]() + fs[1]() * 10;",
+			"21\n",
+		},
+		{"Return from inside for-in", "fun first(xs) { var y = 0; for (var x in xs) return x + y; } print first([7, 8]);", "7\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vm := NewVM(&loxerror.Collector{})
+			if output := run(t, vm, test.source); output != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestVMGlobalsPersist(t *testing.T) {
+	vm := NewVM(&loxerror.Collector{})
+	run(t, vm, "var a = 1; fun inc() { a = a + 1; }")
+	run(t, vm, "inc();")
+	if output := run(t, vm, "print a;"); output != "2\n" {
+		t.Errorf("Expected globals to persist, got %q", output)
+	}
+}
+
+func TestVMRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		output  string
+		message string
+		line    int
+	}{
+		{"Bad operand", "print 1;\nprint -\"a\";", "1\n", "operand must be a number", 2},
+		{"Bad operands", "1 < nil;", "", "operands must be numbers", 1},
+		{"Bad addition", "1 +\n\"a\";", "", "operands must be two numbers or two strings for + operator.", 1},
+		{"Undefined variable", "print x;", "", "Undefined variable 'x'.", 1},
+		{"Assign undefined variable", "x = 1;", "", "Undefined variable 'x'.", 1},
+		{"Call non-callable", "\"f\"();", "", "Can only call functions and classes.", 1},
+		{"Arity", "fun f(a, b) {}\nf(1);", "", "Expected 2 arguments but got 1.", 2},
+		{"Class arity", "class A {}\nA(1);", "", "Expected 0 arguments but got 1.", 2},
+		{"Property on non-instance", "var a = 1;\nprint a.b;", "", "Only instances have properties.", 2},
+		{"Field on non-instance", "nil.b = 1;", "", "Only instances have fields.", 1},
+		{"Undefined property", "class A {}\nA().b;", "", "Undefined property 'b'.", 2},
+		{"Bad superclass", "var A = 1;\nclass B < A {}", "", "Superclass must be a class.", 2},
+		{"Error inside a call", "fun f() {\n  return nil + 1;\n}\nf();", "", "operands must be two numbers or two strings for + operator.", 2},
+		{"Stack overflow", "fun f() { f(); }\nf();", "", "Stack overflow.", 1},
+		{"Native arity", "print 1;\nclock(1);", "1\n", "Expected 0 arguments but got 1.", 2},
+		{"Native error", "len(\n1);", "", "Argument 1 to 'len' must be a string, a list, or a map.", 2},
+		{"Index non-list", "var a = 1;\nprint a[0];", "", "Only lists and maps can be indexed.", 2},
+		{"Fractional index", "var a = [1];\na[0.5];", "", "List index must be an integer.", 2},
+		{"Negative index", "[1][-1] = 2;", "", "List index can't be negative.", 1},
+		{"Index out of bounds", "print [1, 2][2];", "", "List index 2 is out of bounds for a list of length 2.", 1},
+		{"Pop empty list", "[].pop();", "", "Can't pop from an empty list.", 1},
+		{"Bad slice", "[1].slice(0, 2);", "", "Slice [0, 2) out of range for a list of length 1.", 1},
+		{"Undefined list method", "[].size;", "", "Undefined property 'size'.", 1},
+		{"Undefined key", "var m = {\"a\": 1};\nprint m[\"b\"];", "", "Undefined key 'b'.", 2},
+		{"List as key", "var m = {};\nm[[]] = 1;", "", "Can't use a list as a map key.", 2},
+		{"NaN as key", "({(0/0): 1});", "", "Can't use NaN as a map key.", 1},
+		{"Function as key", "fun f() {}\n({}).has(f);", "", "Can't use a function as a map key.", 2},
+		{"Undefined map method", "({}).size;", "", "Undefined property 'size'.", 1},
+		{"Not iterable", "print 1;\nfor (var x in 5) print x;", "1\n", "Can only iterate over lists, maps, strings, and instances with an iter() method.", 2},
+		{"Instance without iter", "class C {}\nfor (var x in C()) print x;", "", "Can only iterate over lists, maps, strings, and instances with an iter() method.", 2},
+		{"Iterator without next", "class C { iter() { return this; } hasNext() {} }\nfor (var x in C()) print x;", "", "iter() must return an instance with hasNext() and next() methods.", 2},
+		{"Error inside next", "class C { iter() { return this; } hasNext() { return true; } next() {\n  return -nil; } }\nfor (var x in C()) print x;", "", "operand must be a number", 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reporter := &loxerror.Collector{}
+			vm := NewVM(reporter)
+			if output := run(t, vm, test.source); output != test.output {
+				t.Errorf("Expected output %q, got %q", test.output, output)
+			}
+			if len(reporter.Diagnostics) != 1 {
+				t.Fatalf("Expected 1 diagnostic, got %v", reporter.Diagnostics)
+			}
+			d := reporter.Diagnostics[0]
+			if d.Message != test.message || d.Line != test.line || d.Code != loxerror.CodeRuntime {
+				t.Errorf("Expected %q on line %d, got %+v", test.message, test.line, d)
+			}
+		})
+	}
+}
+
+func TestVMRecoversAfterError(t *testing.T) {
+	vm := NewVM(&loxerror.Collector{})
+	run(t, vm, "fun f() { var a = 1; return a + nil; } f();")
+	if output := run(t, vm, "var b = 2; print b;"); output != "2\n" {
+		t.Errorf("Expected the VM to run again after an error, got %q", output)
+	}
+}